@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"github.com/ghjm/dyson/pkg/blueprint"
+	"github.com/ghjm/dyson/pkg/dyson"
+	"github.com/spf13/cobra"
+	"io"
+	"os"
+)
+
+func (a *app) blueprintCmd() *cobra.Command {
+	var building string
+	var factories bool
+	var noRecipe bool
+	cmd := &cobra.Command{
+		Use:   "blueprint item:rate",
+		Short: "Generate a game blueprint string for a row of buildings making one item",
+		Long: "Generate a blueprint string, which can be pasted into the game, for a row of smelters or assemblers " +
+			"making one item.  The buildings are fed from one input belt and put their products onto one output " +
+			"belt, with the belt and sorter tiers chosen to keep up with the rate.  The buildings' recipe can only " +
+			"be set if the data gives recipe IDs, as data made by the import command does, so without them the " +
+			"command fails unless --no-recipe is given.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			df, err := a.loadResearchedData()
+			if err != nil {
+				return err
+			}
+			var opts []dyson.ChainOption
+			if building != "" {
+				facType, ok := df.FacilityType(building)
+				if !ok {
+					return fmt.Errorf("unknown building: %s", building)
+				}
+				opts = append(opts, dyson.WithBuilding(facType, building))
+			}
+			res, err := df.NewPlanner(dyson.WithTargets(args...), dyson.WithRateUnit(a.unit),
+				dyson.WithFactoryRates(factories), dyson.WithChainOptions(opts...)).Chain()
+			if err != nil {
+				return err
+			}
+			ch := res.Chain
+			row, err := blueprint.NewRow(&ch.Steps[0])
+			if err != nil {
+				return fmt.Errorf("error laying out blueprint: %w", err)
+			}
+			if row.RecipeID == 0 && !noRecipe {
+				return fmt.Errorf("the data has no recipe ID for %s, so the buildings would have no recipe: use --data "+
+					"with a file made by the import command, or --no-recipe to set the recipe in the game", row.Target)
+			}
+			s, err := row.Blueprint().Encode()
+			if err != nil {
+				return fmt.Errorf("error encoding blueprint: %w", err)
+			}
+			fmt.Fprintln(os.Stderr, row)
+			fmt.Println(s)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&building, "building", "", "Building to use, e.g. \"Plane Smelter\" (default: the chain's usual building)")
+	cmd.Flags().BoolVar(&factories, "factories", false, "Interpret the rate as a number of the chosen building instead of items per second")
+	cmd.Flags().BoolVar(&noRecipe, "no-recipe", false, "Generate the blueprint even if the data has no recipe ID, leaving the buildings without a recipe")
+	a.addResearchFlags(cmd)
+	cmd.AddCommand(a.blueprintInspectCmd())
+	return cmd
+}
+
+func (a *app) blueprintInspectCmd() *cobra.Command {
+	var savePlan string
+	cmd := &cobra.Command{
+		Use:   "inspect [blueprint]",
+		Short: "Count the buildings in a blueprint and find what limits its output",
+		Long: "Decode a blueprint string, count its buildings by type and recipe, and work out what it produces " +
+			"and what limits it, as the plan bottleneck command does.  The blueprint is read from standard input " +
+			"if it isn't given.  Recipes are matched by their IDs, so the data must give them, as data made by " +
+			"the import command does.",
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			df, err := a.loadResearchedData()
+			if err != nil {
+				return err
+			}
+			var s string
+			if len(args) == 1 {
+				s = args[0]
+			} else {
+				data, err := io.ReadAll(os.Stdin)
+				if err != nil {
+					return fmt.Errorf("error reading blueprint: %w", err)
+				}
+				s = string(data)
+			}
+			bp, err := blueprint.Parse(s)
+			if err != nil {
+				return fmt.Errorf("error reading blueprint: %w", err)
+			}
+			in, err := blueprint.Inspect(bp, df)
+			if in != nil {
+				fmt.Print(in)
+			} else {
+				for _, c := range bp.Counts() {
+					fmt.Printf("%d %s\n", c.Count, c.Building)
+				}
+			}
+			if err != nil {
+				return fmt.Errorf("error inspecting blueprint: %w", err)
+			}
+			if savePlan != "" {
+				data, err := in.Plan.Marshal()
+				if err != nil {
+					return err
+				}
+				err = os.WriteFile(savePlan, data, 0o644)
+				if err != nil {
+					return fmt.Errorf("error writing plan: %w", err)
+				}
+			}
+			ba, err := df.Bottlenecks(in.Plan)
+			if err != nil {
+				return fmt.Errorf("error analysing blueprint: %w", err)
+			}
+			fmt.Println()
+			fmt.Print(ba.StringWithOpts(a.displayOpts()...))
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&savePlan, "save-plan", "", "Also write the blueprint as a plan file, for use with the plan commands")
+	a.addResearchFlags(cmd)
+	return cmd
+}
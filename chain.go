@@ -0,0 +1,282 @@
+package main
+
+import (
+	"fmt"
+	"github.com/ghjm/dyson/pkg/dyson"
+	"github.com/spf13/cobra"
+	"maps"
+	"slices"
+	"strings"
+)
+
+func (a *app) chainCmd() *cobra.Command {
+	var haveItems []string
+	var factoriesMode bool
+	var rawByTarget bool
+	var chainFormat string
+	var collapse bool
+	cmd := &cobra.Command{
+		Use:   "chain",
+		Short: "Calculate production chain for a given list of items.  Give item:rate to specify a target rate.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return fmt.Errorf("no targets given")
+			}
+			if collapse && chainFormat != "tree" {
+				return fmt.Errorf("--collapse only works with --format tree")
+			}
+			return a.runOrWatch(func() error {
+				df, err := a.loadResearchedData()
+				if err != nil {
+					return err
+				}
+				res, err := df.NewPlanner(dyson.WithTargets(args...), dyson.WithHave(haveItems...),
+					dyson.WithRateUnit(a.unit), dyson.WithFactoryRates(factoriesMode)).Chain()
+				if err != nil {
+					return err
+				}
+				ch := res.Chain
+				opts := a.displayOpts()
+				if factoriesMode {
+					opts = append(opts, dyson.WithUnitConverter(
+						func(item string, rate float64) (bool, float64, string) {
+							newRate, err := df.ItemsPerSecondToFactories(item, rate)
+							if err != nil {
+								return false, 0, ""
+							} else {
+								return true, newRate, " factories"
+							}
+						}))
+				}
+				if collapse {
+					opts = append(opts, dyson.WithCollapsedSubchains())
+				}
+				switch chainFormat {
+				case "list":
+					fmt.Printf("%s", ch.StringWithOpts(opts...))
+				case "tree":
+					fmt.Printf("%s", ch.TreeString(opts...))
+				case "csv":
+					fmt.Print(ch.CSV(a.displayOpts()...))
+					return nil
+				case "markdown":
+					fmt.Print(ch.MarkdownTable(a.displayOpts()...))
+					return nil
+				default:
+					return fmt.Errorf("unknown format: %s", chainFormat)
+				}
+				return printRawResources(ch, rawByTarget, a.displayOpts()...)
+			})
+		},
+	}
+	cmd.Flags().StringArrayVar(&haveItems, "have", []string{}, "Items you already have (excludes them from the chain)")
+	cmd.Flags().BoolVar(&factoriesMode, "factories", false, "Interpret rates as number of factories instead of items per second")
+	cmd.Flags().BoolVar(&rawByTarget, "by-target", false, "Show how much of each raw resource goes into each target")
+	cmd.Flags().StringVar(&chainFormat, "format", "list", "Output format: list, tree to show what feeds what, or csv or markdown for a table")
+	cmd.Flags().BoolVar(&collapse, "collapse", false, "In tree format, show repeated sub-chains as references")
+	cmd.Flags().BoolVar(&a.watch, "watch", false, "Recalculate the chain whenever a --data file changes")
+	a.addResearchFlags(cmd)
+	return cmd
+}
+
+func (a *app) graphCmd() *cobra.Command {
+	var haveItems []string
+	var graphFormat string
+	cmd := &cobra.Command{
+		Use:   "graph",
+		Short: "Generate a Mermaid or SVG graph capturing the production dependencies for a given set of targets",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return fmt.Errorf("no targets given")
+			}
+			return a.runOrWatch(func() error {
+				df, err := a.loadResearchedData()
+				if err != nil {
+					return err
+				}
+				res, err := df.NewPlanner(dyson.WithTargets(args...), dyson.WithHave(haveItems...),
+					dyson.WithRateUnit(a.unit)).Chain()
+				if err != nil {
+					return err
+				}
+				ch := res.Chain
+				switch graphFormat {
+				case "mermaid":
+					fmt.Print(ch.MermaidGraph())
+				case "svg":
+					fmt.Print(ch.SVG(a.displayOpts()...))
+				default:
+					return fmt.Errorf("unknown format: %s", graphFormat)
+				}
+				return nil
+			})
+		},
+	}
+	cmd.Flags().StringArrayVar(&haveItems, "have", []string{}, "Items you already have (excludes them from the graph)")
+	cmd.Flags().StringVar(&graphFormat, "format", "mermaid", "Output format: mermaid, or svg for an image")
+	cmd.Flags().BoolVar(&a.watch, "watch", false, "Draw the graph again whenever a --data file changes")
+	a.addResearchFlags(cmd)
+	return cmd
+}
+
+func (a *app) makesCmd() *cobra.Command {
+	var categories []string
+	cmd := &cobra.Command{
+		Use:   "makes",
+		Short: "Calculate what can be produced from a given list of items",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			df, err := a.loadResearchedData()
+			if err != nil {
+				return err
+			}
+			cats, err := parseCategories(categories)
+			if err != nil {
+				return err
+			}
+			ch := df.NewChain(args)
+			err = ch.GetAllProducible()
+			if err != nil {
+				return fmt.Errorf("error filling chain: %w", err)
+			}
+			fmt.Printf("%s", ch.StringWithOpts(append(a.displayOpts(), dyson.WithCategories(cats...))...))
+			return nil
+		},
+	}
+	cmd.Flags().StringArrayVar(&categories, "category", []string{},
+		"Only list items in this category (resource, component, building, matrix, fuel, combat)")
+	a.addResearchFlags(cmd)
+	return cmd
+}
+
+func (a *app) diffCmd() *cobra.Command {
+	var oldItems []string
+	var newItems []string
+	var oldExcludes []string
+	var newExcludes []string
+	var categories []string
+	cmd := &cobra.Command{
+		Use:   "diff",
+		Short: "Calculate what additional items can be produced when adding a new resource",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			df, err := a.loadResearchedData()
+			if err != nil {
+				return err
+			}
+			cats, err := parseCategories(categories)
+			if err != nil {
+				return err
+			}
+			res, err := df.NewPlanner(dyson.WithDiff(oldItems, newItems, oldExcludes, newExcludes)).Diff()
+			if err != nil {
+				return err
+			}
+			for _, di := range res.Diff.Added {
+				if df.InCategory(di.Item, cats...) {
+					fmt.Println(di.String())
+				}
+			}
+			var removed []dyson.DiffItem
+			for _, di := range res.Diff.Removed {
+				if df.InCategory(di.Item, cats...) {
+					removed = append(removed, di)
+				}
+			}
+			if len(removed) > 0 {
+				fmt.Println("\nNo longer made:")
+				for _, di := range removed {
+					fmt.Println(di.String())
+				}
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringArrayVar(&oldItems, "old", []string{}, "old items")
+	cmd.Flags().StringArrayVar(&newItems, "new", []string{}, "new items")
+	cmd.Flags().StringArrayVar(&oldExcludes, "exclude-old", []string{}, "banned items")
+	cmd.Flags().StringArrayVar(&newExcludes, "exclude-new", []string{}, "banned items")
+	cmd.Flags().StringArrayVar(&categories, "category", []string{},
+		"Only list items in this category (resource, component, building, matrix, fuel, combat)")
+	a.addResearchFlags(cmd)
+	return cmd
+}
+
+func (a *app) mallCmd() *cobra.Command {
+	var busItems []string
+	cmd := &cobra.Command{
+		Use:   "mall item:rate...",
+		Short: "Plan a mall that keeps buildings stocked from the items on the bus",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			df, err := a.loadResearchedData()
+			if err != nil {
+				return err
+			}
+			plan, err := df.NewPlanner(dyson.WithTargets(args...), dyson.WithRateUnit(a.unit)).Plan()
+			if err != nil {
+				return err
+			}
+			var targets []dyson.MallTarget
+			for _, t := range plan.Targets {
+				targets = append(targets, dyson.MallTarget{Item: t.Item, Rate: t.Rate})
+			}
+			mall, err := df.PlanMall(targets, busItems)
+			if err != nil {
+				return fmt.Errorf("error planning mall: %w", err)
+			}
+			fmt.Print(mall.StringWithOpts(a.displayOpts()...))
+			return nil
+		},
+	}
+	cmd.Flags().StringArrayVar(&busItems, "bus", []string{}, "Items available on the bus")
+	a.addResearchFlags(cmd)
+	return cmd
+}
+
+// parseCategories converts --category flag values to item categories
+func parseCategories(names []string) ([]dyson.ItemCategory, error) {
+	var cats []dyson.ItemCategory
+	for _, name := range names {
+		cat, err := dyson.ParseItemCategory(name)
+		if err != nil {
+			return nil, err
+		}
+		cats = append(cats, cat)
+	}
+	return cats, nil
+}
+
+// printRawResources prints the raw resources a chain pulls from the planet and the supplied items it uses, with the
+// raw resources optionally broken down by target
+func printRawResources(ch *dyson.ProductionChain, byTarget bool, opts ...dyson.StringOption) error {
+	formatRates := func(rates map[string]float64, sep string) string {
+		var parts []string
+		for _, item := range slices.Sorted(maps.Keys(rates)) {
+			parts = append(parts, fmt.Sprintf("%s: %s", item, dyson.FormatRate(rates[item], opts...)))
+		}
+		return strings.Join(parts, sep)
+	}
+	if supplied := ch.Supplied(); len(supplied) > 0 {
+		fmt.Printf("\nSupplied:\n  %s\n", formatRates(supplied, "\n  "))
+	}
+	raw := ch.RawResources()
+	if len(raw) == 0 {
+		return nil
+	}
+	fmt.Printf("\nRaw resources:\n  %s\n", formatRates(raw, "\n  "))
+	if !byTarget {
+		return nil
+	}
+	attributed, err := ch.RawResourcesByTarget()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("\nRaw resources by target:\n")
+	for _, step := range ch.Steps {
+		rates, ok := attributed[step.Target]
+		if !ok || len(rates) == 0 {
+			continue
+		}
+		fmt.Printf("  %s: %s\n", step.Target, formatRates(rates, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ghjm/dyson/pkg/dspimport"
+	"github.com/ghjm/dyson/pkg/dyson"
+	"github.com/spf13/cobra"
+	"os"
+)
+
+func (a *app) validateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "validate",
+		Short: "Validate that the data file is correct",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return a.runOrWatch(func() error {
+				df, err := a.loadData()
+				if err == nil {
+					err = df.Validate()
+				}
+				var verr *dyson.ValidationError
+				if errors.As(err, &verr) {
+					for _, p := range verr.Problems {
+						fmt.Println(p)
+					}
+					return fmt.Errorf("validation found %d problem(s)", len(verr.Problems))
+				}
+				if err != nil {
+					return err
+				}
+				fmt.Println("Validation successful!")
+				return nil
+			})
+		},
+	}
+	cmd.Flags().BoolVar(&a.watch, "watch", false, "Validate again whenever a --data file changes")
+	return cmd
+}
+
+func (a *app) importCmd() *cobra.Command {
+	var output string
+	cmd := &cobra.Command{
+		Use:   "import dump.json",
+		Short: "Convert a JSON dump of the game's item and recipe prototypes into a data file",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			df, err := a.loadData()
+			if err != nil {
+				return err
+			}
+			dump, err := os.ReadFile(args[0])
+			if err != nil {
+				return fmt.Errorf("error reading dump: %w", err)
+			}
+			imported, err := dspimport.Convert(dump, df.Facilities)
+			if err != nil {
+				return fmt.Errorf("error importing dump: %w", err)
+			}
+			var verr *dyson.ValidationError
+			if errors.As(imported.Validate(), &verr) {
+				for _, p := range verr.Problems {
+					fmt.Fprintf(os.Stderr, "warning: %s\n", p)
+				}
+			}
+			data, err := imported.Marshal()
+			if err != nil {
+				return err
+			}
+			if output == "" {
+				fmt.Print(string(data))
+				return nil
+			}
+			err = os.WriteFile(output, data, 0o644)
+			if err != nil {
+				return fmt.Errorf("error writing data file: %w", err)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&output, "output", "o", "", "File to write the data to (default stdout)")
+	return cmd
+}
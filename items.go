@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"github.com/ghjm/dyson/pkg/dyson"
+	"github.com/spf13/cobra"
+	"maps"
+	"slices"
+	"strings"
+)
+
+func (a *app) itemCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "item name",
+		Short: "Show how an item is made and what uses it",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			df, err := a.loadData()
+			if err != nil {
+				return err
+			}
+			name := args[0]
+			if !df.HasItem(name) {
+				return fmt.Errorf("unknown item: %s", name)
+			}
+			item, hasItem := df.Item(name)
+			producers := df.Producers(name)
+			consumers := df.Consumers(name)
+			fmt.Println(name)
+			if hasItem {
+				fmt.Printf("  Category: %s\n", item.Category)
+				if item.Stack > 0 {
+					fmt.Printf("  Stack size: %d\n", item.Stack)
+				}
+				if item.Fuel > 0 {
+					fmt.Printf("  Fuel value: %s MJ\n", dyson.FormatNumber(item.Fuel, a.displayOpts()...))
+				}
+				if item.Tech != "" {
+					fmt.Printf("  Unlocked by: %s\n", item.Tech)
+				}
+			}
+
+			fmt.Println("\nMade by:")
+			if len(producers) == 0 {
+				fmt.Println("  nothing")
+			}
+			for _, proc := range producers {
+				fmt.Printf("  %s\n", proc.Recipe())
+				for _, facType := range proc.Facility {
+					buildings := df.Facilities[facType]
+					for _, b := range slices.Sorted(maps.Keys(buildings)) {
+						fmt.Printf("    %s: %s\n", b, dyson.FormatRate(proc.ItemsPerSecond(name, buildings[b]),
+							a.displayOpts()...))
+					}
+				}
+			}
+
+			fmt.Println("\nUsed by:")
+			if len(consumers) == 0 {
+				fmt.Println("  nothing")
+			}
+			for _, proc := range consumers {
+				fmt.Printf("  %s\n", proc.Recipe())
+			}
+			return nil
+		},
+	}
+}
+
+func (a *app) usesCmd() *cobra.Command {
+	var depth int
+	cmd := &cobra.Command{
+		Use:   "uses item",
+		Short: "List the processes that consume an item, and optionally what uses their products in turn",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			df, err := a.loadResearchedData()
+			if err != nil {
+				return err
+			}
+			uses := df.Uses(args[0], depth)
+			if len(uses) == 0 {
+				fmt.Printf("Nothing uses %s\n", args[0])
+				return nil
+			}
+			for _, u := range uses {
+				makes := slices.Sorted(maps.Keys(u.Process.Makes))
+				inputs := slices.Sorted(maps.Keys(u.Process.Consumes))
+				special := ""
+				if u.Process.Special {
+					special = " [special]"
+				}
+				fmt.Printf("%s%s: %s%s\n", strings.Repeat("  ", u.Depth-1), strings.Join(makes, ", "),
+					strings.Join(inputs, ", "), special)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().IntVar(&depth, "depth", 1, "How many steps of indirect uses to follow (0 for no limit)")
+	a.addResearchFlags(cmd)
+	return cmd
+}
+
+func (a *app) resourcesCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "resources",
+		Short: "Lists items that can be directly mined, pumped, etc.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			df, err := a.loadData()
+			if err != nil {
+				return err
+			}
+			for _, proc := range df.Processes {
+				if len(proc.Consumes) == 0 {
+					for m := range proc.Makes {
+						fmt.Println(m)
+					}
+				}
+			}
+			return nil
+		},
+	}
+}
+
+func (a *app) techCmd() *cobra.Command {
+	techCmd := &cobra.Command{
+		Use:   "tech",
+		Short: "Explore the technology tree",
+	}
+	pathCmd := &cobra.Command{
+		Use:   "path technology...",
+		Short: "List the research order and total cost to reach the given technologies",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			df, err := a.loadData()
+			if err != nil {
+				return err
+			}
+			done, err := a.researchedTechs(df)
+			if err != nil {
+				return err
+			}
+			path, err := df.TechPath(args...)
+			if err != nil {
+				return err
+			}
+			var todo []string
+			for _, tech := range path {
+				if !slices.Contains(done, tech) {
+					todo = append(todo, tech)
+					fmt.Println(tech)
+				}
+			}
+			if len(todo) == 0 {
+				fmt.Println("Everything is already researched.")
+				return nil
+			}
+			cost := df.TechCost(todo)
+			var costs []string
+			for _, item := range slices.Sorted(maps.Keys(cost)) {
+				costs = append(costs, fmt.Sprintf("%d %s", cost[item], item))
+			}
+			fmt.Printf("\nTotal cost: %s\n", strings.Join(costs, ", "))
+			return nil
+		},
+	}
+	a.addResearchFlags(pathCmd)
+	techCmd.AddCommand(pathCmd)
+	return techCmd
+}
@@ -1,13 +1,15 @@
 package main
 
 import (
+	"context"
 	_ "embed"
+	"errors"
 	"fmt"
 	"github.com/ghjm/dyson/pkg/dyson"
 	"github.com/spf13/cobra"
 	"os"
-	"strconv"
-	"strings"
+	"os/signal"
+	"slices"
 )
 
 //go:embed data.yml
@@ -17,249 +19,136 @@ var gitCommit string
 
 const gitRepo = "https://github.com/ghjm/dyson"
 
+// app holds the flags shared between commands, and loads the data they describe
+type app struct {
+	dataFiles  []string
+	precision  int
+	unitName   string
+	unit       dyson.RateUnit
+	researched []string
+	upTo       []string
+	watch      bool
+}
+
 func main() {
+	a := &app{unit: dyson.PerSecond}
 	rootCmd := &cobra.Command{
 		Use:          "dyson",
 		Short:        "Dyson Sphere Program CLI calculator",
 		SilenceUsage: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+			a.unit, err = dyson.ParseRateUnit(a.unitName)
+			return err
+		},
 	}
+	rootCmd.PersistentFlags().StringArrayVar(&a.dataFiles, "data", []string{},
+		"path to data file (repeat to layer overlay files on top of each other)")
+	rootCmd.PersistentFlags().IntVar(&a.precision, "precision", dyson.DefaultPrecision,
+		"decimal places to show in rates and counts (-1 for full precision)")
+	rootCmd.PersistentFlags().StringVar(&a.unitName, "unit", string(dyson.PerSecond),
+		"time unit for rates given and shown (s, min or h)")
 
-	var dataFile string
-	rootCmd.PersistentFlags().StringVar(&dataFile, "data", "", "path to data file")
+	rootCmd.AddCommand(a.validateCmd(), a.chainCmd(), a.graphCmd(), a.makesCmd(), a.diffCmd(), a.mallCmd(),
+		a.itemCmd(), a.usesCmd(), a.resourcesCmd(), a.planCmd(), a.compareCmd(), a.reportCmd(), a.blueprintCmd(),
+		a.importCmd(), a.serveCmd(), a.techCmd(), versionCmd())
 
-	loadData := func() (*dyson.DataFile, error) {
-		var data []byte
-		if dataFile == "" {
-			data = dataFileContent
-		} else {
-			var err error
-			data, err = os.ReadFile(dataFile)
-			if err != nil {
-				return nil, fmt.Errorf("error reading data file: %w", err)
-			}
-		}
-		df, err := dyson.LoadData(data)
-		if err != nil {
-			return nil, fmt.Errorf("error loading data: %w", err)
+	if cmd, err := rootCmd.ExecuteC(); err != nil {
+		if hint := errorHint(cmd, err); hint != "" {
+			fmt.Fprintf(os.Stderr, "Hint: %s\n", hint)
 		}
-		return df, nil
+		os.Exit(1)
 	}
+}
 
-	validateCmd := &cobra.Command{
-		Use:   "validate",
-		Short: "Validate that the data file is correct",
-		RunE: func(cmd *cobra.Command, args []string) error {
-			df, err := loadData()
-			if err != nil {
-				return err
-			}
-			err = df.Validate()
-			if err != nil {
-				return fmt.Errorf("error validating data: %w", err)
-			}
-			fmt.Println("Validation successful!")
-			return nil
-		},
-	}
-	rootCmd.AddCommand(validateCmd)
+// displayOpts returns the string options for the display flags
+func (a *app) displayOpts() []dyson.StringOption {
+	return []dyson.StringOption{dyson.WithPrecision(a.precision), dyson.WithDisplayUnit(a.unit)}
+}
 
-	var haveItems []string
-	var factoriesMode bool
-	chainCmd := &cobra.Command{
-		Use:   "chain",
-		Short: "Calculate production chain for a given list of items.  Give item:rate to specify a target rate.",
-		RunE: func(cmd *cobra.Command, args []string) error {
-			df, err := loadData()
-			if err != nil {
-				return err
-			}
-			var reqs []string
-			rates := make(map[string]float32)
-			for _, arg := range args {
-				if strings.Contains(arg, ":") {
-					parts := strings.Split(arg, ":")
-					if len(parts) != 2 {
-						return fmt.Errorf("invalid argument: %s", arg)
-					}
-					pRate, err := strconv.ParseFloat(parts[1], 32)
-					if err != nil {
-						return fmt.Errorf("invalid rate: %s", parts[1])
-					}
-					rate := float32(pRate)
-					if factoriesMode {
-						rate, err = df.FactoriesToItemsPerSecond(parts[0], rate)
-						if err != nil {
-							return fmt.Errorf("error calculating rate: %w", err)
-						}
-					}
-					reqs = append(reqs, parts[0])
-					rates[parts[0]] = rate
-				} else {
-					reqs = append(reqs, arg)
-				}
-			}
-			ch := df.NewChain(reqs)
-			for item, rate := range rates {
-				err = ch.SetRate(item, rate)
-				if err != nil {
-					return fmt.Errorf("error setting rate: %w", err)
-				}
-			}
-			err = ch.FillChainExcluding(haveItems)
+// loadData loads the embedded data and any --data files layered on top of it
+func (a *app) loadData() (*dyson.DataFile, error) {
+	layers := []dyson.DataLayer{{Name: "data.yml (embedded)", Data: dataFileContent}}
+	for i, fn := range a.dataFiles {
+		data, err := os.ReadFile(fn)
+		if err != nil {
+			return nil, fmt.Errorf("error reading data file: %w", err)
+		}
+		if i == 0 {
+			// A full data file given first is used instead of the embedded data
+			overlay, err := dyson.IsOverlay(data)
 			if err != nil {
-				return fmt.Errorf("error filling chain: %w", err)
+				return nil, fmt.Errorf("error loading data: %s: %w", fn, err)
 			}
-			var opts []dyson.StringOption
-			if factoriesMode {
-				opts = append(opts, dyson.WithUnitConverter(
-					func(item string, rate float32) (bool, float32, string) {
-						newRate, err := df.ItemsPerSecondToFactories(item, rate)
-						if err != nil {
-							return false, 0, ""
-						} else {
-							return true, newRate, " factories"
-						}
-					}))
+			if !overlay {
+				layers = nil
 			}
-			fmt.Printf("%s", ch.StringWithOpts(opts...))
-			return nil
-		},
+		}
+		layers = append(layers, dyson.DataLayer{Name: fn, Data: data})
+	}
+	df, err := dyson.LoadDataLayers(layers...)
+	if err != nil {
+		return nil, fmt.Errorf("error loading data: %w", err)
 	}
-	chainCmd.Flags().StringArrayVar(&haveItems, "have", []string{}, "Items you already have (excludes them from the chain)")
-	chainCmd.Flags().BoolVar(&factoriesMode, "factories", false, "Interpret rates as number of factories instead of items per second")
-	rootCmd.AddCommand(chainCmd)
+	return df, nil
+}
 
-	var graphHaveItems []string
-	graphCmd := &cobra.Command{
-		Use:   "graph",
-		Short: "Generate a Mermaid graph capturing the production dependencies for a given set of targets",
-		RunE: func(cmd *cobra.Command, args []string) error {
-			df, err := loadData()
-			if err != nil {
-				return err
-			}
-			var reqs []string
-			rates := make(map[string]float32)
-			for _, arg := range args {
-				if strings.Contains(arg, ":") {
-					parts := strings.Split(arg, ":")
-					if len(parts) != 2 {
-						return fmt.Errorf("invalid argument: %s", arg)
-					}
-					pRate, err := strconv.ParseFloat(parts[1], 32)
-					if err != nil {
-						return fmt.Errorf("invalid rate: %s", parts[1])
-					}
-					rate := float32(pRate)
-					reqs = append(reqs, parts[0])
-					rates[parts[0]] = rate
-				} else {
-					reqs = append(reqs, arg)
-				}
-			}
-			ch := df.NewChain(reqs)
-			for item, rate := range rates {
-				err = ch.SetRate(item, rate)
-				if err != nil {
-					return fmt.Errorf("error setting rate: %w", err)
-				}
-			}
-			err = ch.FillChainExcluding(graphHaveItems)
-			if err != nil {
-				return fmt.Errorf("error filling chain: %w", err)
-			}
-			graph := ch.MermaidGraph()
-			fmt.Print(graph)
-			return nil
-		},
+// runOrWatch runs a command once, or with --watch, again each time a data file changes
+func (a *app) runOrWatch(run func() error) error {
+	if a.watch {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		return watchFiles(ctx, a.dataFiles, os.Stderr, run)
 	}
-	graphCmd.Flags().StringArrayVar(&graphHaveItems, "have", []string{}, "Items you already have (excludes them from the graph)")
-	rootCmd.AddCommand(graphCmd)
+	return run()
+}
 
-	makesCmd := &cobra.Command{
-		Use:   "makes",
-		Short: "Calculate what can be produced from a given list of items",
-		RunE: func(cmd *cobra.Command, args []string) error {
-			df, err := loadData()
-			if err != nil {
-				return err
-			}
-			ch := df.NewChain(args)
-			err = ch.GetAllProducible()
-			if err != nil {
-				return fmt.Errorf("error filling chain: %w", err)
-			}
-			fmt.Printf("%s", ch.String())
-			return nil
-		},
+// addResearchFlags adds --researched and --up-to to the commands that limit themselves to what has been researched
+func (a *app) addResearchFlags(cmds ...*cobra.Command) {
+	for _, cmd := range cmds {
+		cmd.Flags().StringArrayVar(&a.researched, "researched", []string{},
+			"only use processes unlocked by this technology (repeatable)")
+		cmd.Flags().StringArrayVar(&a.upTo, "up-to", []string{},
+			"only use processes unlocked by this technology and its prerequisites (repeatable)")
 	}
-	rootCmd.AddCommand(makesCmd)
+}
 
-	var oldItems []string
-	var newItems []string
-	var oldExcludes []string
-	var newExcludes []string
-	diffCmd := &cobra.Command{
-		Use:   "diff",
-		Short: "Calculate what additional items can be produced when adding a new resource",
-		RunE: func(cmd *cobra.Command, args []string) error {
-			df, err := loadData()
-			if err != nil {
-				return err
-			}
-			var reqs []string
-			reqs = append(reqs, oldItems...)
-			chOld := df.NewChain(reqs)
-			err = chOld.GetAllProducibleExcluding(oldExcludes)
-			if err != nil {
-				return fmt.Errorf("error filling old chain: %w", err)
-			}
-			reqs = append(reqs, newItems...)
-			chNew := df.NewChain(reqs)
-			err = chNew.GetAllProducibleExcluding(newExcludes)
-			if err != nil {
-				return fmt.Errorf("error filling new chain: %w", err)
-			}
-			oldTargets := make(map[string]struct{})
-			for _, s := range chOld.Steps {
-				oldTargets[s.Target] = struct{}{}
-			}
-			for _, s := range chNew.Steps {
-				_, ok := oldTargets[s.Target]
-				if !ok {
-					fmt.Println(s.String())
-				}
-			}
-			return nil
-		},
+// researchedTechs returns the technologies given by --researched and --up-to, or nil if neither was used
+func (a *app) researchedTechs(df *dyson.DataFile) ([]string, error) {
+	if len(a.researched) == 0 && len(a.upTo) == 0 {
+		return nil, nil
 	}
-	diffCmd.Flags().StringArrayVar(&oldItems, "old", []string{}, "old items")
-	diffCmd.Flags().StringArrayVar(&newItems, "new", []string{}, "new items")
-	diffCmd.Flags().StringArrayVar(&oldExcludes, "exclude-old", []string{}, "banned items")
-	diffCmd.Flags().StringArrayVar(&newExcludes, "exclude-new", []string{}, "banned items")
-	rootCmd.AddCommand(diffCmd)
+	techs, err := df.TechPath(a.upTo...)
+	if err != nil {
+		return nil, err
+	}
+	for _, tech := range a.researched {
+		if !slices.Contains(techs, tech) {
+			techs = append(techs, tech)
+		}
+	}
+	return techs, nil
+}
 
-	resourcesCmd := &cobra.Command{
-		Use:   "resources",
-		Short: "Lists items that can be directly mined, pumped, etc.",
-		RunE: func(cmd *cobra.Command, args []string) error {
-			df, err := loadData()
-			if err != nil {
-				return err
-			}
-			for _, proc := range df.Processes {
-				if len(proc.Consumes) == 0 {
-					for m := range proc.Makes {
-						fmt.Println(m)
-					}
-				}
-			}
-			return nil
-		},
+// loadResearchedData loads the data, restricted to the processes unlocked by --researched and --up-to
+func (a *app) loadResearchedData() (*dyson.DataFile, error) {
+	df, err := a.loadData()
+	if err != nil {
+		return nil, err
+	}
+	techs, err := a.researchedTechs(df)
+	if err != nil || techs == nil {
+		return df, err
 	}
-	rootCmd.AddCommand(resourcesCmd)
+	df, err = df.WithResearched(techs)
+	if err != nil {
+		return nil, fmt.Errorf("error applying research: %w", err)
+	}
+	return df, nil
+}
 
-	versionCmd := &cobra.Command{
+func versionCmd() *cobra.Command {
+	return &cobra.Command{
 		Use:   "version",
 		Short: "Shows the git commit this was built from",
 		Run: func(cmd *cobra.Command, args []string) {
@@ -272,9 +161,58 @@ func main() {
 			fmt.Printf("\n")
 		},
 	}
-	rootCmd.AddCommand(versionCmd)
+}
 
-	if err := rootCmd.Execute(); err != nil {
-		os.Exit(1)
+// errorHint suggests how to get past a chain construction error in a command, or returns "" if there is nothing to
+// suggest
+func errorHint(cmd *cobra.Command, err error) string {
+	supply := supplyHint(cmd)
+	var unknown *dyson.ErrUnknownItem
+	var noRecipe *dyson.ErrNoRecipe
+	var onlySpecial *dyson.ErrOnlySpecialRecipes
+	var cycle *dyson.ErrCycle
+	switch {
+	case errors.As(err, &unknown):
+		return fmt.Sprintf("%q is not in the data.  Item names must match exactly, including case and "+
+			"punctuation such as \"Mk. II\".", unknown.Item)
+	case errors.As(err, &noRecipe) && len(noRecipe.Inputs) > 0:
+		return fmt.Sprintf("Run \"dyson item %s\" to see the processes that make it and what they consume.",
+			noRecipe.Item)
+	case errors.As(err, &noRecipe):
+		hint := fmt.Sprintf("Nothing available makes %s.  If you used --researched or --up-to, the technology "+
+			"that unlocks it may not be included", noRecipe.Item)
+		if supply != "" {
+			hint += "; otherwise " + supply
+		}
+		return hint + "."
+	case errors.As(err, &onlySpecial):
+		hint := fmt.Sprintf("Only special processes make %s.  Choose one in a plan's recipes", onlySpecial.Item)
+		if supply != "" {
+			hint += ", or " + supply + " if you get it some other way"
+		}
+		return hint + "."
+	case errors.As(err, &cycle):
+		hint := fmt.Sprintf("The loop that makes %s uses at least as much of it as it makes.  Choose a recipe for "+
+			"it in a plan that doesn't feed back into the loop", cycle.Item)
+		if supply != "" {
+			hint += ", or " + supply
+		}
+		return hint + "."
+	}
+	return ""
+}
+
+// supplyHint says how to supply an item from outside the chain in a command, or returns "" if the command can't
+func supplyHint(cmd *cobra.Command) string {
+	switch {
+	case cmd == nil:
+		return ""
+	case cmd.Parent() != nil && cmd.Parent().Name() == "plan", cmd.Name() == "compare", cmd.Flags().Changed("plan"):
+		return "add it to the plan's have list"
+	case cmd.Flags().Lookup("have") != nil:
+		return "list it with --have"
+	case cmd.Flags().Lookup("bus") != nil:
+		return "list it with --bus"
 	}
+	return ""
 }
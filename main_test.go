@@ -0,0 +1,51 @@
+package main
+
+import (
+	"github.com/ghjm/dyson/pkg/dyson"
+	"strings"
+	"testing"
+)
+
+func TestErrorHint(t *testing.T) {
+	a := &app{}
+	planCmd := a.planCmd()
+	runCmd, _, err := planCmd.Find([]string{"run"})
+	if err != nil {
+		t.Fatalf("Failed to find plan run: %v", err)
+	}
+	reportWithPlan := a.reportCmd()
+	err = reportWithPlan.Flags().Set("plan", "plan.yml")
+	if err != nil {
+		t.Fatalf("Failed to set --plan: %v", err)
+	}
+	noRecipe := &dyson.ErrNoRecipe{Item: "Gear"}
+
+	tests := []struct {
+		name string
+		hint string
+		want string // "" if the hint must not say how to supply the item
+	}{
+		{"chain", errorHint(a.chainCmd(), noRecipe), "otherwise list it with --have."},
+		{"report", errorHint(a.reportCmd(), &dyson.ErrCycle{Item: "Gear"}), "or list it with --have."},
+		{"report with a plan", errorHint(reportWithPlan, noRecipe), "otherwise add it to the plan's have list."},
+		{"plan run", errorHint(runCmd, &dyson.ErrOnlySpecialRecipes{Item: "Gear"}),
+			"or add it to the plan's have list if you get it some other way."},
+		{"compare", errorHint(a.compareCmd(), noRecipe), "otherwise add it to the plan's have list."},
+		{"mall", errorHint(a.mallCmd(), noRecipe), "otherwise list it with --bus."},
+		{"blueprint", errorHint(a.blueprintCmd(), noRecipe), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.want == "" {
+				if strings.Contains(tt.hint, "--have") || strings.Contains(tt.hint, "have list") ||
+					!strings.HasSuffix(tt.hint, "may not be included.") {
+					t.Errorf("errorHint() = %q, want no way to supply the item", tt.hint)
+				}
+				return
+			}
+			if !strings.HasSuffix(tt.hint, tt.want) {
+				t.Errorf("errorHint() = %q, want it to end %q", tt.hint, tt.want)
+			}
+		})
+	}
+}
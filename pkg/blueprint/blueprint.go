@@ -0,0 +1,135 @@
+// Package blueprint reads and writes Dyson Sphere Program blueprint strings.
+//
+// A blueprint string is a comma separated header, then the gzipped binary data of the blueprint in base64 between
+// double quotes, and last an MD5F hash of everything before the closing quote:
+//
+//	BLUEPRINT:0,10,2303,0,0,0,0,0,638000000000000000,0.10.30.22292,Gears,"H4sIAAAA..."0123456789ABCDEF...
+package blueprint
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GameVersion is the game version written to new blueprints
+const GameVersion = "0.10.30.22292"
+
+// DefaultLayout is the icon layout of new blueprints, which shows a single icon
+const DefaultLayout = 10
+
+const prefix = "BLUEPRINT:"
+
+// ticksAtUnixEpoch is the .NET tick count, in 100ns units since year 1, at the start of 1970
+const ticksAtUnixEpoch = 621355968000000000
+
+// Blueprint is a decoded blueprint string
+type Blueprint struct {
+	Layout      int
+	Icons       [5]int // item IDs shown as the blueprint's icons, 0 for none
+	Time        time.Time
+	GameVersion string
+	ShortDesc   string
+	Desc        string
+	Data        Data
+}
+
+// Parse decodes a blueprint string, checking its hash
+func Parse(s string) (*Blueprint, error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, prefix) {
+		return nil, fmt.Errorf("not a blueprint string: missing %s prefix", strings.TrimSuffix(prefix, ":"))
+	}
+	first, last := strings.Index(s, `"`), strings.LastIndex(s, `"`)
+	if first < 0 || first == last {
+		return nil, fmt.Errorf("not a blueprint string: missing quoted data")
+	}
+	if hash := s[last+1:]; !strings.EqualFold(hash, md5f(s[:last])) {
+		return nil, fmt.Errorf("blueprint hash does not match: the string may be incomplete or damaged")
+	}
+
+	fields := strings.Split(s[len(prefix):first], ",")
+	if len(fields) < 11 {
+		return nil, fmt.Errorf("blueprint header has %d fields, want at least 11", len(fields))
+	}
+	bp := &Blueprint{GameVersion: fields[9]}
+	var err error
+	bp.Layout, err = strconv.Atoi(fields[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid blueprint layout: %w", err)
+	}
+	for i := range bp.Icons {
+		bp.Icons[i], err = strconv.Atoi(fields[2+i])
+		if err != nil {
+			return nil, fmt.Errorf("invalid blueprint icon: %w", err)
+		}
+	}
+	ticks, err := strconv.ParseInt(fields[8], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid blueprint time: %w", err)
+	}
+	ticks -= ticksAtUnixEpoch
+	bp.Time = time.Unix(ticks/1e7, ticks%1e7*100).UTC()
+	bp.ShortDesc, err = url.PathUnescape(fields[10])
+	if err != nil {
+		return nil, fmt.Errorf("invalid blueprint description: %w", err)
+	}
+	if len(fields) > 11 {
+		bp.Desc, err = url.PathUnescape(fields[11])
+		if err != nil {
+			return nil, fmt.Errorf("invalid blueprint description: %w", err)
+		}
+	}
+
+	compressed, err := base64.StdEncoding.DecodeString(s[first+1 : last])
+	if err != nil {
+		return nil, fmt.Errorf("invalid blueprint data: %w", err)
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, fmt.Errorf("invalid blueprint data: %w", err)
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid blueprint data: %w", err)
+	}
+	err = bp.Data.UnmarshalBinary(data)
+	if err != nil {
+		return nil, err
+	}
+	return bp, nil
+}
+
+// Encode writes the blueprint as a string the game can import
+func (bp *Blueprint) Encode() (string, error) {
+	data, err := bp.Data.MarshalBinary()
+	if err != nil {
+		return "", err
+	}
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	_, err = zw.Write(data)
+	if err != nil {
+		return "", fmt.Errorf("could not compress blueprint: %w", err)
+	}
+	err = zw.Close()
+	if err != nil {
+		return "", fmt.Errorf("could not compress blueprint: %w", err)
+	}
+
+	fields := []string{"0", strconv.Itoa(bp.Layout)}
+	for _, icon := range bp.Icons {
+		fields = append(fields, strconv.Itoa(icon))
+	}
+	ticks := bp.Time.Unix()*1e7 + int64(bp.Time.Nanosecond()/100) + ticksAtUnixEpoch
+	fields = append(fields, "0", strconv.FormatInt(ticks, 10), bp.GameVersion, url.PathEscape(bp.ShortDesc),
+		url.PathEscape(bp.Desc))
+	s := prefix + strings.Join(fields, ",") + `"` + base64.StdEncoding.EncodeToString(compressed.Bytes())
+	return s + `"` + md5f(s), nil
+}
@@ -0,0 +1,147 @@
+package blueprint
+
+import (
+	"crypto/md5"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMD5Sum(t *testing.T) {
+	// With the standard constants, md5Sum must agree with MD5, so that MD5F only differs by its constants
+	for _, s := range []string{"", "a", "BLUEPRINT:0,10", strings.Repeat("x", 55), strings.Repeat("y", 64),
+		strings.Repeat("z", 1000)} {
+		if got, want := md5Sum([]byte(s), md5Init, &md5Table), md5.Sum([]byte(s)); got != want {
+			t.Errorf("md5Sum(%q) = %x, want %x", s, got, want)
+		}
+		if md5Sum([]byte(s), md5fInit, &md5fTable) == md5.Sum([]byte(s)) {
+			t.Errorf("MD5F of %q is the same as MD5", s)
+		}
+	}
+	if got := md5f("abc"); len(got) != 32 || strings.ToUpper(got) != got {
+		t.Errorf("md5f() = %q, want 32 upper case hex digits", got)
+	}
+}
+
+func testBlueprint() *Blueprint {
+	return &Blueprint{
+		Layout:      DefaultLayout,
+		Icons:       [5]int{2303, 0, 0, 0, 1101},
+		Time:        time.Date(2024, 5, 6, 7, 8, 9, 1234500, time.UTC),
+		GameVersion: GameVersion,
+		ShortDesc:   "Gears, 100% \"fast\"",
+		Desc:        "Line one\nline two",
+		Data: Data{
+			Version:        1,
+			CursorOffsetX:  3,
+			CursorOffsetY:  -2,
+			DragBoxSizeX:   7,
+			DragBoxSizeY:   7,
+			PrimaryAreaIdx: 0,
+			Areas:          []Area{{Index: 0, ParentIndex: -1, AreaSegments: 200, Width: 7, Height: 7}},
+			Buildings: []Building{
+				{Index: 0, LocalOffset: [3]float32{1, 2, 0}, LocalOffset2: [3]float32{1, 2, 0}, ItemID: 2303,
+					ModelIndex: 65, OutputObjIdx: -1, InputObjIdx: -1, RecipeID: 5},
+				{Index: 1, LocalOffset: [3]float32{0.5, -1.25, 0}, LocalOffset2: [3]float32{0.5, 0.75, 0}, Yaw: 180,
+					Yaw2: 180, ItemID: 2011, ModelIndex: 41, OutputObjIdx: 0, InputObjIdx: -1, OutputToSlot: -1,
+					InputFromSlot: -1, InputToSlot: 1, FilterID: 1101, Parameters: []int32{1, -2, 3}},
+			},
+		},
+	}
+}
+
+func TestBlueprint_RoundTrip(t *testing.T) {
+	bp := testBlueprint()
+	s, err := bp.Encode()
+	if err != nil {
+		t.Fatalf("Failed to encode blueprint: %v", err)
+	}
+	if !strings.HasPrefix(s, "BLUEPRINT:0,10,2303,0,0,0,1101,0,638505760890012345,"+GameVersion+",") {
+		t.Errorf("Encode() header is wrong: %s", s)
+	}
+	if strings.Count(s, `"`) != 2 {
+		t.Errorf("Encode() should only quote the data: %s", s)
+	}
+	got, err := Parse(s)
+	if err != nil {
+		t.Fatalf("Failed to parse blueprint: %v", err)
+	}
+	if !reflect.DeepEqual(got, bp) {
+		t.Errorf("Parse(Encode()) = %+v, want %+v", got, bp)
+	}
+	again, err := got.Encode()
+	if err != nil {
+		t.Fatalf("Failed to encode blueprint: %v", err)
+	}
+	if again != s {
+		t.Errorf("second Encode() differs:\n%s\n%s", again, s)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	s, err := testBlueprint().Encode()
+	if err != nil {
+		t.Fatalf("Failed to encode blueprint: %v", err)
+	}
+	last := strings.LastIndex(s, `"`)
+	tests := []struct {
+		name, s, err string
+	}{
+		{"not a blueprint", "hello", "missing BLUEPRINT prefix"},
+		{"no data", "BLUEPRINT:0,10", "missing quoted data"},
+		{"changed header", strings.Replace(s, "Gears", "Cogs", 1), "hash does not match"},
+		{"truncated", s[:last-4] + s[last:], "hash does not match"},
+		{"bad hash", s[:last+1] + strings.Repeat("0", 32), "hash does not match"},
+		{"short header", `BLUEPRINT:0,10,0"AAAA"` + md5f(`BLUEPRINT:0,10,0"AAAA`), "header has 3 fields"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.s)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("Parse() error = %v, want one containing %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestData_Formats(t *testing.T) {
+	data, err := testBlueprint().Data.MarshalBinary()
+	if err != nil {
+		t.Fatalf("Failed to marshal data: %v", err)
+	}
+	var d Data
+	for n := range data {
+		if err := d.UnmarshalBinary(data[:n]); err == nil {
+			t.Fatalf("UnmarshalBinary() of %d of %d bytes succeeded", n, len(data))
+		}
+	}
+
+	// The newer building format starts with a marker, and stores the tilt after the yaw
+	b := Building{Index: 4, Yaw: 90, Yaw2: 90, Tilt: 12.5, ItemID: 2001, ModelIndex: 35, OutputObjIdx: -1,
+		InputObjIdx: -1}
+	old, err := (&Data{Buildings: []Building{b}}).MarshalBinary()
+	if err != nil {
+		t.Fatalf("Failed to marshal data: %v", err)
+	}
+	const headerSize = 7*4 + 1 + 4
+	newer := append([]byte(nil), old[:headerSize]...)
+	newer = append(newer, 0x9c, 0xff, 0xff, 0xff) // -100
+	newer = append(newer, old[headerSize:headerSize+4+1+8*4]...)
+	newer = append(newer, 0, 0, 0x48, 0x41) // 12.5
+	newer = append(newer, old[headerSize+4+1+8*4:]...)
+	err = d.UnmarshalBinary(newer)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal newer format: %v", err)
+	}
+	if len(d.Buildings) != 1 || !reflect.DeepEqual(d.Buildings[0], b) {
+		t.Errorf("UnmarshalBinary() = %+v, want %+v", d.Buildings, b)
+	}
+
+	// Formats after the tilt format aren't supported
+	newer[headerSize] = 0x9b // -101
+	err = d.UnmarshalBinary(newer)
+	if err == nil || !strings.Contains(err.Error(), "unsupported building format -101") {
+		t.Errorf("UnmarshalBinary() error = %v, want an unsupported building format", err)
+	}
+}
@@ -0,0 +1,64 @@
+package blueprint
+
+import (
+	"maps"
+	"slices"
+)
+
+// model is how the game identifies a kind of building: the item it is built from, and the model it is drawn with
+type model struct {
+	itemID     int16
+	modelIndex int16
+}
+
+// models are the buildings that blueprints made or read by this package can contain, by their names in the data file
+var models = map[string]model{
+	"Conveyor Belt Mk. I":         {2001, 35},
+	"Conveyor Belt Mk. II":        {2002, 36},
+	"Conveyor Belt Mk. III":       {2003, 37},
+	"Sorter Mk. I":                {2011, 41},
+	"Sorter Mk. II":               {2012, 42},
+	"Sorter Mk. III":              {2013, 43},
+	"Arc Smelter":                 {2302, 62},
+	"Assembling Machine Mk. I":    {2303, 65},
+	"Assembling Machine Mk. II":   {2304, 66},
+	"Assembling Machine Mk. III":  {2305, 67},
+	"Oil Refinery":                {2308, 63},
+	"Chemical Plant":              {2309, 64},
+	"Miniature Particle Collider": {2310, 69},
+	"Fractionator":                {2314, 119},
+	"Plane Smelter":               {2315, 194},
+	"Quantum Chemical Plant":      {2317, 376},
+	"Matrix Lab":                  {2901, 70},
+}
+
+// belts and sorters are the conveyor belts and sorters, slowest first, with how many items per second they move.
+// Sorter speeds are for a sorter reaching across one grid cell.
+var (
+	belts = []struct {
+		name string
+		rate float64
+	}{
+		{"Conveyor Belt Mk. I", 6},
+		{"Conveyor Belt Mk. II", 12},
+		{"Conveyor Belt Mk. III", 30},
+	}
+	sorters = []struct {
+		name string
+		rate float64
+	}{
+		{"Sorter Mk. I", 1.5},
+		{"Sorter Mk. II", 3},
+		{"Sorter Mk. III", 6},
+	}
+)
+
+// buildingName returns the name of the building made from an item, if it is one this package knows
+func buildingName(itemID int16) (string, bool) {
+	for _, name := range slices.Sorted(maps.Keys(models)) {
+		if models[name].itemID == itemID {
+			return name, true
+		}
+	}
+	return "", false
+}
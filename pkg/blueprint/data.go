@@ -0,0 +1,219 @@
+package blueprint
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Data is the binary content of a blueprint: the areas of the planet grid it covers and the buildings in them
+type Data struct {
+	Version          int32
+	CursorOffsetX    int32
+	CursorOffsetY    int32
+	CursorTargetArea int32
+	DragBoxSizeX     int32
+	DragBoxSizeY     int32
+	PrimaryAreaIdx   int32
+	Areas            []Area
+	Buildings        []Building
+}
+
+// Area is a rectangle of the planet grid that buildings are placed in
+type Area struct {
+	Index              int8
+	ParentIndex        int8
+	TropicAnchor       int16
+	AreaSegments       int16
+	AnchorLocalOffsetX int16
+	AnchorLocalOffsetY int16
+	Width              int16
+	Height             int16
+}
+
+// Building is one building in a blueprint.  Offsets are in grid cells within the building's area, and connections to
+// other buildings are by their index, or -1 for none.  For sorters and belts, LocalOffset is where the building
+// starts and LocalOffset2 where it ends.
+type Building struct {
+	Index          int32
+	AreaIndex      int8
+	LocalOffset    [3]float32
+	LocalOffset2   [3]float32
+	Yaw            float32
+	Yaw2           float32
+	Tilt           float32 // only stored in the newer building format
+	ItemID         int16
+	ModelIndex     int16
+	OutputObjIdx   int32
+	InputObjIdx    int32
+	OutputToSlot   int8
+	InputFromSlot  int8
+	OutputFromSlot int8
+	InputToSlot    int8
+	OutputOffset   int8
+	InputOffset    int8
+	RecipeID       int16
+	FilterID       int16
+	Parameters     []int32
+}
+
+// tiltFormat marks a building stored in the newer format, which includes the tilt.  Newer game versions mark
+// buildings with further formats, such as -101, whose layout isn't known here, so they are rejected.
+const tiltFormat = -100
+
+// dataHeader is the fixed part at the start of the data
+type dataHeader struct {
+	Version          int32
+	CursorOffsetX    int32
+	CursorOffsetY    int32
+	CursorTargetArea int32
+	DragBoxSizeX     int32
+	DragBoxSizeY     int32
+	PrimaryAreaIdx   int32
+}
+
+// buildingPlacement and buildingLinks are the fixed parts of a building before and after its item and model
+type buildingPlacement struct {
+	AreaIndex    int8
+	LocalOffset  [3]float32
+	LocalOffset2 [3]float32
+	Yaw          float32
+	Yaw2         float32
+}
+
+type buildingLinks struct {
+	OutputObjIdx   int32
+	InputObjIdx    int32
+	OutputToSlot   int8
+	InputFromSlot  int8
+	OutputFromSlot int8
+	InputToSlot    int8
+	OutputOffset   int8
+	InputOffset    int8
+	RecipeID       int16
+	FilterID       int16
+}
+
+// MarshalBinary encodes the data in the layout the game reads.  Buildings are written in the older format, without
+// tilt, which the game still accepts.
+func (d *Data) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	w := func(v any) {
+		_ = binary.Write(&buf, binary.LittleEndian, v)
+	}
+	w(dataHeader{d.Version, d.CursorOffsetX, d.CursorOffsetY, d.CursorTargetArea, d.DragBoxSizeX, d.DragBoxSizeY,
+		d.PrimaryAreaIdx})
+	if len(d.Areas) > 127 {
+		return nil, fmt.Errorf("too many areas: %d", len(d.Areas))
+	}
+	w(int8(len(d.Areas)))
+	for _, a := range d.Areas {
+		w(a)
+	}
+	w(int32(len(d.Buildings)))
+	for _, b := range d.Buildings {
+		if b.Index < 0 {
+			return nil, fmt.Errorf("building index is negative: %d", b.Index)
+		}
+		if len(b.Parameters) > 32767 {
+			return nil, fmt.Errorf("building %d: too many parameters", b.Index)
+		}
+		w(b.Index)
+		w(buildingPlacement{b.AreaIndex, b.LocalOffset, b.LocalOffset2, b.Yaw, b.Yaw2})
+		w(b.ItemID)
+		w(b.ModelIndex)
+		w(buildingLinks{b.OutputObjIdx, b.InputObjIdx, b.OutputToSlot, b.InputFromSlot, b.OutputFromSlot,
+			b.InputToSlot, b.OutputOffset, b.InputOffset, b.RecipeID, b.FilterID})
+		w(int16(len(b.Parameters)))
+		w(b.Parameters)
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary decodes data in the layout the game writes.  Buildings may be in the older format or the tilt
+// format; any other building format is an error.
+func (d *Data) UnmarshalBinary(data []byte) error {
+	r := bytes.NewReader(data)
+	var err error
+	read := func(v any) {
+		if err == nil {
+			err = binary.Read(r, binary.LittleEndian, v)
+		}
+	}
+	var h dataHeader
+	read(&h)
+	*d = Data{Version: h.Version, CursorOffsetX: h.CursorOffsetX, CursorOffsetY: h.CursorOffsetY,
+		CursorTargetArea: h.CursorTargetArea, DragBoxSizeX: h.DragBoxSizeX, DragBoxSizeY: h.DragBoxSizeY,
+		PrimaryAreaIdx: h.PrimaryAreaIdx}
+	var areaCount int8
+	read(&areaCount)
+	if err == nil && areaCount < 0 {
+		return fmt.Errorf("invalid area count: %d", areaCount)
+	}
+	d.Areas = make([]Area, max(areaCount, 0))
+	read(d.Areas)
+	var buildingCount int32
+	read(&buildingCount)
+	if err != nil {
+		return dataError(err)
+	}
+	if buildingCount < 0 || int64(buildingCount) > int64(r.Len()) {
+		return fmt.Errorf("invalid building count: %d", buildingCount)
+	}
+	d.Buildings = make([]Building, buildingCount)
+	for i := range d.Buildings {
+		b := &d.Buildings[i]
+		var marker int32
+		read(&marker)
+		if err != nil {
+			break
+		}
+		switch {
+		case marker >= 0:
+			b.Index = marker
+		case marker == tiltFormat:
+			read(&b.Index)
+		default:
+			return fmt.Errorf("building %d: unsupported building format %d: only the formats up to %d are supported",
+				i, marker, tiltFormat)
+		}
+		var p buildingPlacement
+		read(&p)
+		b.AreaIndex, b.LocalOffset, b.LocalOffset2, b.Yaw, b.Yaw2 = p.AreaIndex, p.LocalOffset, p.LocalOffset2, p.Yaw,
+			p.Yaw2
+		if marker == tiltFormat {
+			read(&b.Tilt)
+		}
+		read(&b.ItemID)
+		read(&b.ModelIndex)
+		var l buildingLinks
+		read(&l)
+		b.OutputObjIdx, b.InputObjIdx, b.RecipeID, b.FilterID = l.OutputObjIdx, l.InputObjIdx, l.RecipeID, l.FilterID
+		b.OutputToSlot, b.InputFromSlot, b.OutputFromSlot, b.InputToSlot = l.OutputToSlot, l.InputFromSlot,
+			l.OutputFromSlot, l.InputToSlot
+		b.OutputOffset, b.InputOffset = l.OutputOffset, l.InputOffset
+		var paramCount int16
+		read(&paramCount)
+		if err == nil && paramCount < 0 {
+			return fmt.Errorf("building %d: invalid parameter count: %d", i, paramCount)
+		}
+		if paramCount > 0 {
+			b.Parameters = make([]int32, paramCount)
+			read(b.Parameters)
+		}
+	}
+	if err != nil {
+		return dataError(err)
+	}
+	return nil
+}
+
+// dataError describes an error reading blueprint data
+func dataError(err error) error {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return fmt.Errorf("blueprint data is truncated")
+	}
+	return fmt.Errorf("could not read blueprint data: %w", err)
+}
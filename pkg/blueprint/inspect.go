@@ -0,0 +1,171 @@
+package blueprint
+
+import (
+	"cmp"
+	"fmt"
+	"github.com/ghjm/dyson/pkg/dyson"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// BuildingCount is how many of one kind of building a blueprint has, set to one recipe
+type BuildingCount struct {
+	Building string // name of the building, or "item" and its item ID if it isn't one this package knows
+	RecipeID int    // 0 for buildings with no recipe, such as belts and sorters
+	Count    int
+}
+
+// Inspection is what a blueprint builds, and the plan that evaluates it as a production chain
+type Inspection struct {
+	Counts  []BuildingCount // every building in the blueprint, by building and recipe
+	Unknown []BuildingCount // buildings set to a recipe that isn't in the data
+	Items   map[int]string  // the item each known recipe is run for, by recipe ID
+	Plan    *dyson.Plan     // the blueprint's processes and building counts, with its final products as targets
+}
+
+// Counts counts the buildings in the blueprint by building and recipe
+func (bp *Blueprint) Counts() []BuildingCount {
+	type key struct {
+		itemID   int16
+		recipeID int16
+	}
+	counts := make(map[key]int)
+	for _, b := range bp.Data.Buildings {
+		counts[key{b.ItemID, b.RecipeID}]++
+	}
+	var result []BuildingCount
+	for k, n := range counts {
+		name, ok := buildingName(k.itemID)
+		if !ok {
+			name = "item " + strconv.Itoa(int(k.itemID))
+		}
+		result = append(result, BuildingCount{Building: name, RecipeID: int(k.recipeID), Count: n})
+	}
+	slices.SortFunc(result, func(a, b BuildingCount) int {
+		return cmp.Or(cmp.Compare(a.Building, b.Building), cmp.Compare(a.RecipeID, b.RecipeID))
+	})
+	return result
+}
+
+// Inspect works out the processes a blueprint's buildings run, using the recipe IDs in the data, and makes a plan
+// with a built count for each item.  Where buildings of different tiers make the same item, the count is in terms
+// of the facility type's most common building, so two Mk. I assemblers count as one and a half Mk. II.  Items the
+// blueprint uses but doesn't make are supplied, and items it makes but doesn't use are the plan's targets, each at
+// the full output of its buildings.
+func Inspect(bp *Blueprint, df *dyson.DataFile) (*Inspection, error) {
+	if !slices.ContainsFunc(df.Processes, func(p dyson.Process) bool { return p.ID != 0 }) {
+		return nil, fmt.Errorf("the data has no recipe IDs to match the blueprint's buildings to: use data made by " +
+			"the import command")
+	}
+	in := &Inspection{Counts: bp.Counts(), Items: make(map[int]string)}
+
+	type placed struct {
+		count    BuildingCount
+		process  *dyson.Process
+		item     string
+		facility string
+	}
+	var steps []placed
+	buildingsOf := make(map[string]map[string]int) // facility type to building to count
+	for _, c := range in.Counts {
+		if c.RecipeID == 0 {
+			continue
+		}
+		proc, ok := df.ProcessByID(c.RecipeID)
+		facType, known := df.FacilityType(c.Building)
+		if !ok || !known {
+			in.Unknown = append(in.Unknown, c)
+			continue
+		}
+		if !slices.Contains(proc.Facility, facType) {
+			return nil, fmt.Errorf("%s is set to recipe %d, which is not made in a %s", c.Building, c.RecipeID,
+				facType)
+		}
+		steps = append(steps, placed{count: c, process: proc, item: mainProduct(proc), facility: facType})
+		in.Items[c.RecipeID] = mainProduct(proc)
+		if buildingsOf[facType] == nil {
+			buildingsOf[facType] = make(map[string]int)
+		}
+		buildingsOf[facType][c.Building] += c.Count
+	}
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("blueprint has no buildings with a recipe from the data")
+	}
+
+	in.Plan = &dyson.Plan{
+		Recipes:   make(map[string][]string),
+		Buildings: make(map[string]string),
+		Built:     make(map[string]float64),
+	}
+	for facType, counts := range buildingsOf {
+		in.Plan.Buildings[facType] = slices.MaxFunc(slices.Sorted(maps.Keys(counts)), func(a, b string) int {
+			return cmp.Or(cmp.Compare(counts[a], counts[b]), strings.Compare(b, a))
+		})
+	}
+	recipeFor := make(map[string]int)
+	made := make(map[string]struct{})
+	used := make(map[string]struct{})
+	for _, s := range steps {
+		if other, ok := recipeFor[s.item]; ok && other != s.count.RecipeID {
+			return nil, fmt.Errorf("blueprint makes %s with more than one recipe (%d and %d)", s.item, other,
+				s.count.RecipeID)
+		}
+		recipeFor[s.item] = s.count.RecipeID
+		speed := df.Facilities[s.facility][s.count.Building]
+		chosen := df.Facilities[s.facility][in.Plan.Buildings[s.facility]]
+		in.Plan.Built[s.item] += float64(s.count.Count) * speed / chosen
+		if len(s.process.Consumes) > 0 {
+			in.Plan.Recipes[s.item] = slices.Sorted(maps.Keys(s.process.Consumes))
+		}
+		made[s.item] = struct{}{}
+		for item := range s.process.Consumes {
+			used[item] = struct{}{}
+		}
+	}
+	for _, item := range slices.Sorted(maps.Keys(used)) {
+		if _, ok := made[item]; !ok {
+			in.Plan.Have = append(in.Plan.Have, item)
+		}
+	}
+	for _, item := range slices.Sorted(maps.Keys(made)) {
+		if _, ok := used[item]; !ok {
+			in.Plan.Targets = append(in.Plan.Targets, dyson.PlanTarget{Item: item, Factories: in.Plan.Built[item]})
+		}
+	}
+	if len(in.Plan.Targets) == 0 {
+		return nil, fmt.Errorf("blueprint uses everything it makes, so it has no final product")
+	}
+	return in, nil
+}
+
+// mainProduct returns the item a process is run for: the first it makes that it doesn't also consume
+func mainProduct(proc *dyson.Process) string {
+	items := slices.Sorted(maps.Keys(proc.Makes))
+	for _, item := range items {
+		if _, ok := proc.Consumes[item]; !ok {
+			return item
+		}
+	}
+	return items[0]
+}
+
+// String lists the blueprint's buildings, e.g. "2 Assembling Machine Mk. II: Gear (recipe 5)"
+func (in *Inspection) String() string {
+	sb := strings.Builder{}
+	for _, c := range in.Counts {
+		sb.WriteString(fmt.Sprintf("%d %s", c.Count, c.Building))
+		if c.RecipeID != 0 {
+			if item, ok := in.Items[c.RecipeID]; ok {
+				sb.WriteString(": " + item)
+			}
+			sb.WriteString(fmt.Sprintf(" (recipe %d)", c.RecipeID))
+		}
+		if slices.Contains(in.Unknown, c) {
+			sb.WriteString(" [not in the data]")
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
@@ -0,0 +1,121 @@
+package blueprint
+
+import (
+	"github.com/ghjm/dyson/pkg/dyson"
+	"math"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// inspectTestBlueprint has 3 Mk. II and 2 Mk. I assemblers making gears, 3 smelters making iron ingots, a building
+// with a recipe the data doesn't know, and a belt
+func inspectTestBlueprint() *Blueprint {
+	bp := &Blueprint{}
+	add := func(n int, building string, recipe int16) {
+		for i := 0; i < n; i++ {
+			m := models[building]
+			bp.Data.Buildings = append(bp.Data.Buildings, Building{Index: int32(len(bp.Data.Buildings)),
+				ItemID: m.itemID, ModelIndex: m.modelIndex, OutputObjIdx: -1, InputObjIdx: -1, RecipeID: recipe})
+		}
+	}
+	add(3, "Assembling Machine Mk. II", 5)
+	add(2, "Assembling Machine Mk. I", 5)
+	add(3, "Arc Smelter", 1)
+	add(1, "Assembling Machine Mk. II", 99)
+	add(4, "Conveyor Belt Mk. I", 0)
+	return bp
+}
+
+func TestBlueprint_Counts(t *testing.T) {
+	got := inspectTestBlueprint().Counts()
+	want := []BuildingCount{
+		{"Arc Smelter", 1, 3},
+		{"Assembling Machine Mk. I", 5, 2},
+		{"Assembling Machine Mk. II", 5, 3},
+		{"Assembling Machine Mk. II", 99, 1},
+		{"Conveyor Belt Mk. I", 0, 4},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Counts() = %v, want %v", got, want)
+	}
+
+	bp := &Blueprint{Data: Data{Buildings: []Building{{ItemID: 1234}}}}
+	if got := bp.Counts(); len(got) != 1 || got[0].Building != "item 1234" {
+		t.Errorf("Counts() = %v, want an item 1234", got)
+	}
+}
+
+func TestInspect(t *testing.T) {
+	df := loadRowTestData(t)
+	in, err := Inspect(inspectTestBlueprint(), df)
+	if err != nil {
+		t.Fatalf("Failed to inspect blueprint: %v", err)
+	}
+	if len(in.Unknown) != 1 || in.Unknown[0].RecipeID != 99 {
+		t.Errorf("Inspect() unknown = %v, want recipe 99", in.Unknown)
+	}
+	p := in.Plan
+	if p.Buildings["assembler"] != "Assembling Machine Mk. II" || p.Buildings["smelter"] != "Arc Smelter" {
+		t.Errorf("Inspect() buildings = %v", p.Buildings)
+	}
+	// Two Mk. I assemblers do the work of one and a half Mk. II
+	if p.Built["Gear"] != 4.5 || p.Built["Iron Ingot"] != 3 {
+		t.Errorf("Inspect() built = %v", p.Built)
+	}
+	if len(p.Targets) != 1 || p.Targets[0].Item != "Gear" || p.Targets[0].Factories != 4.5 {
+		t.Errorf("Inspect() targets = %v", p.Targets)
+	}
+	if !slices.Equal(p.Have, []string{"Iron Ore"}) {
+		t.Errorf("Inspect() have = %v", p.Have)
+	}
+	for _, s := range []string{"3 Arc Smelter: Iron Ingot (recipe 1)\n",
+		"2 Assembling Machine Mk. I: Gear (recipe 5)\n", "1 Assembling Machine Mk. II (recipe 99) [not in the data]\n",
+		"4 Conveyor Belt Mk. I\n"} {
+		if !strings.Contains(in.String(), s) {
+			t.Errorf("String() missing %q:\n%s", s, in)
+		}
+	}
+
+	// The gear assemblers need 4.5 ingots a second, but the smelters only make 3
+	ba, err := df.Bottlenecks(p)
+	if err != nil {
+		t.Fatalf("Failed to analyse plan: %v", err)
+	}
+	if math.Abs(ba.Scale-2.0/3) > 1e-9 || !slices.Equal(ba.Bottlenecks, []string{"Iron Ingot"}) {
+		t.Errorf("Bottlenecks() scale = %v, bottlenecks = %v", ba.Scale, ba.Bottlenecks)
+	}
+}
+
+func TestInspect_Errors(t *testing.T) {
+	df := loadRowTestData(t)
+	tests := []struct {
+		name      string
+		buildings []Building
+		err       string
+	}{
+		{"no recipes", []Building{{ItemID: 2001}}, "no buildings with a recipe"},
+		{"wrong facility", []Building{{ItemID: 2302, RecipeID: 5}}, "not made in a smelter"},
+		{"two recipes", []Building{{ItemID: 2304, RecipeID: 5}, {ItemID: 2303, RecipeID: 7}},
+			"more than one recipe (7 and 5)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Inspect(&Blueprint{Data: Data{Buildings: tt.buildings}}, df)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("Inspect() error = %v, want one containing %q", err, tt.err)
+			}
+		})
+	}
+
+	// Data without recipe IDs, such as the embedded data, can't be matched to a blueprint at all
+	noIDs, err := dyson.LoadData([]byte(strings.ReplaceAll(rowTestYAMLData, "id:", "# id:")))
+	if err != nil {
+		t.Fatalf("Failed to load test data: %v", err)
+	}
+	_, err = Inspect(inspectTestBlueprint(), noIDs)
+	if err == nil || !strings.Contains(err.Error(), "the data has no recipe IDs") {
+		t.Errorf("Inspect() error = %v, want one about recipe IDs", err)
+	}
+}
@@ -0,0 +1,100 @@
+package blueprint
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"math"
+	"math/bits"
+	"strings"
+)
+
+// md5Shifts are the per-round rotation amounts of MD5
+var md5Shifts = [64]int{
+	7, 12, 17, 22, 7, 12, 17, 22, 7, 12, 17, 22, 7, 12, 17, 22,
+	5, 9, 14, 20, 5, 9, 14, 20, 5, 9, 14, 20, 5, 9, 14, 20,
+	4, 11, 16, 23, 4, 11, 16, 23, 4, 11, 16, 23, 4, 11, 16, 23,
+	6, 10, 15, 21, 6, 10, 15, 21, 6, 10, 15, 21, 6, 10, 15, 21,
+}
+
+// md5Init and md5Table are the initial state and round constants of standard MD5
+var (
+	md5Init  = [4]uint32{0x67452301, 0xefcdab89, 0x98badcfe, 0x10325476}
+	md5Table [64]uint32
+)
+
+// md5fInit and md5fTable are the initial state and round constants of MD5F, the variant of MD5 that the game uses to
+// check blueprint strings.  It differs from MD5 in one word of the initial state and eight of the round constants.
+var (
+	md5fInit  = [4]uint32{0x67452301, 0xefdcab89, 0x98badcfe, 0x10325476}
+	md5fTable [64]uint32
+)
+
+func init() {
+	for i := range md5Table {
+		md5Table[i] = uint32(math.Floor(math.Abs(math.Sin(float64(i+1))) * (1 << 32)))
+	}
+	md5fTable = md5Table
+	for i, v := range map[int]uint32{
+		1:  0xe8d7b756,
+		6:  0xa8304623,
+		12: 0x6b9f1122,
+		15: 0x39b40821,
+		19: 0xc9b6c7aa,
+		21: 0x02443453,
+		24: 0x21f1cde6,
+		27: 0x475a14ed,
+	} {
+		md5fTable[i] = v
+	}
+}
+
+// md5Sum hashes data with MD5, given the initial state and round constants to use
+func md5Sum(data []byte, state [4]uint32, table *[64]uint32) [16]byte {
+	msg := append([]byte(nil), data...)
+	msg = append(msg, 0x80)
+	for len(msg)%64 != 56 {
+		msg = append(msg, 0)
+	}
+	msg = binary.LittleEndian.AppendUint64(msg, uint64(len(data))*8)
+
+	var m [16]uint32
+	for chunk := 0; chunk < len(msg); chunk += 64 {
+		for i := range m {
+			m[i] = binary.LittleEndian.Uint32(msg[chunk+4*i:])
+		}
+		a, b, c, d := state[0], state[1], state[2], state[3]
+		for i := 0; i < 64; i++ {
+			var f uint32
+			var g int
+			switch i / 16 {
+			case 0:
+				f, g = (b&c)|(^b&d), i
+			case 1:
+				f, g = (d&b)|(^d&c), (5*i+1)%16
+			case 2:
+				f, g = b^c^d, (3*i+5)%16
+			default:
+				f, g = c^(b|^d), (7*i)%16
+			}
+			f += a + table[i] + m[g]
+			a, d, c = d, c, b
+			b += bits.RotateLeft32(f, md5Shifts[i])
+		}
+		state[0] += a
+		state[1] += b
+		state[2] += c
+		state[3] += d
+	}
+
+	var sum [16]byte
+	for i, v := range state {
+		binary.LittleEndian.PutUint32(sum[4*i:], v)
+	}
+	return sum
+}
+
+// md5f returns the MD5F hash of a string as upper case hex, as it appears at the end of a blueprint string
+func md5f(s string) string {
+	sum := md5Sum([]byte(s), md5fInit, &md5fTable)
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
@@ -0,0 +1,193 @@
+package blueprint
+
+import (
+	"fmt"
+	"github.com/ghjm/dyson/pkg/dyson"
+	"math"
+	"time"
+)
+
+// maxSortersPerSide is how many sorters fit along one side of a smelter or assembler
+const maxSortersPerSide = 3
+
+// buildingPitch is the distance between the centres of neighbouring buildings in a row, in grid cells
+const buildingPitch = 3
+
+// Row is a production step laid out as a line of identical buildings, fed by sorters from an input belt on one side
+// and unloaded by sorters onto an output belt on the other.  All of the step's inputs share the input belt, and all
+// of its products share the output belt.
+type Row struct {
+	Target        string
+	Building      string
+	RecipeID      int     // 0 if the data doesn't give the recipe's ID, which leaves the buildings without a recipe
+	Count         int     // number of buildings
+	InputRate     float64 // items per second on the input belt
+	OutputRate    float64 // items per second on the output belt
+	Belt          string
+	Sorter        string
+	InputSorters  int // sorters per building taking from the input belt
+	OutputSorters int // sorters per building putting onto the output belt
+}
+
+// NewRow works out the buildings, belts and sorters needed to lay out a step as a row.  Only smelter and assembler
+// steps can be laid out, and the step must fit on one input belt and one output belt.
+func NewRow(step *dyson.ProductionStep) (*Row, error) {
+	if step.Process == nil || step.Building == "" {
+		return nil, fmt.Errorf("%s is not made by a building", step.Target)
+	}
+	if step.Facility != "smelter" && step.Facility != "assembler" {
+		return nil, fmt.Errorf("%s is made in a %s: only smelter and assembler rows can be laid out", step.Target,
+			step.Facility)
+	}
+	if _, ok := models[step.Building]; !ok {
+		return nil, fmt.Errorf("no blueprint model known for %s", step.Building)
+	}
+	if step.Rate <= 0 {
+		return nil, fmt.Errorf("%s has no rate", step.Target)
+	}
+	r := &Row{
+		Target:   step.Target,
+		Building: step.Building,
+		RecipeID: step.Process.ID,
+		Count:    int(math.Ceil(step.Factories() - 1e-9)),
+	}
+	runs := step.Rate / float64(step.Process.Makes[step.Target])
+	for _, count := range step.Process.Consumes {
+		r.InputRate += runs * float64(count)
+	}
+	for _, count := range step.Process.Makes {
+		r.OutputRate += runs * float64(count)
+	}
+
+	beltRate := max(r.InputRate, r.OutputRate)
+	for _, b := range belts {
+		if beltRate <= b.rate {
+			r.Belt = b.name
+			break
+		}
+	}
+	if r.Belt == "" {
+		return nil, fmt.Errorf("%s needs %s items/s on one belt, more than the fastest belt carries", step.Target,
+			dyson.FormatNumber(beltRate))
+	}
+	for _, s := range sorters {
+		in := int(math.Ceil(r.InputRate/float64(r.Count)/s.rate - 1e-9))
+		out := int(math.Ceil(r.OutputRate/float64(r.Count)/s.rate - 1e-9))
+		if in <= maxSortersPerSide && out <= maxSortersPerSide {
+			r.Sorter, r.InputSorters, r.OutputSorters = s.name, in, out
+			break
+		}
+	}
+	if r.Sorter == "" {
+		return nil, fmt.Errorf("each %s needs more sorters than fit around it", step.Building)
+	}
+	return r, nil
+}
+
+// Blueprint lays out the row.  Items flow left to right along both belts, with the input belt below the buildings
+// and the output belt above them.
+func (r *Row) Blueprint() *Blueprint {
+	const inputY, buildingY, outputY = 0, 3, 6
+	length := buildingPitch * r.Count
+	var buildings []Building
+	add := func(b Building) int32 {
+		b.Index = int32(len(buildings))
+		buildings = append(buildings, b)
+		return b.Index
+	}
+	addBelt := func(y float32) []int32 {
+		var idx []int32
+		belt := models[r.Belt]
+		for x := 0; x <= length; x++ {
+			i := add(Building{
+				LocalOffset:  [3]float32{float32(x), y, 0},
+				LocalOffset2: [3]float32{float32(x), y, 0},
+				Yaw:          90,
+				Yaw2:         90,
+				ItemID:       belt.itemID,
+				ModelIndex:   belt.modelIndex,
+				OutputObjIdx: -1,
+				InputObjIdx:  -1,
+				OutputToSlot: 1, InputFromSlot: -1, OutputFromSlot: 0, InputToSlot: 1,
+			})
+			if len(idx) > 0 {
+				buildings[idx[len(idx)-1]].OutputObjIdx = i
+			}
+			idx = append(idx, i)
+		}
+		return idx
+	}
+	inputBelt := addBelt(inputY)
+	outputBelt := addBelt(outputY)
+
+	building, sorter := models[r.Building], models[r.Sorter]
+	addSorters := func(n int, centre int, fromY, toY float32, from func(x int) int32, to func(x int) int32) {
+		for s := 0; s < n; s++ {
+			x := centre + s - 1
+			add(Building{
+				LocalOffset:  [3]float32{float32(x), fromY, 0},
+				LocalOffset2: [3]float32{float32(x), toY, 0},
+				ItemID:       sorter.itemID,
+				ModelIndex:   sorter.modelIndex,
+				OutputObjIdx: to(x),
+				InputObjIdx:  from(x),
+				OutputToSlot: -1, InputFromSlot: -1, OutputFromSlot: 0, InputToSlot: 1,
+			})
+		}
+	}
+	for i := 0; i < r.Count; i++ {
+		centre := buildingPitch*i + 1
+		b := add(Building{
+			LocalOffset:  [3]float32{float32(centre), buildingY, 0},
+			LocalOffset2: [3]float32{float32(centre), buildingY, 0},
+			ItemID:       building.itemID,
+			ModelIndex:   building.modelIndex,
+			OutputObjIdx: -1,
+			InputObjIdx:  -1,
+			RecipeID:     int16(r.RecipeID),
+		})
+		toBuilding := func(int) int32 { return b }
+		addSorters(r.InputSorters, centre, inputY+0.8, buildingY-1.2, func(x int) int32 { return inputBelt[x] },
+			toBuilding)
+		addSorters(r.OutputSorters, centre, buildingY+1.2, outputY-0.8, toBuilding,
+			func(x int) int32 { return outputBelt[x] })
+	}
+
+	return &Blueprint{
+		Layout:      DefaultLayout,
+		Icons:       [5]int{int(building.itemID)},
+		Time:        time.Now().UTC(),
+		GameVersion: GameVersion,
+		ShortDesc:   fmt.Sprintf("%d %s: %s", r.Count, r.Building, r.Target),
+		Desc: fmt.Sprintf("%s items/s in on %s, %s items/s out", dyson.FormatNumber(r.InputRate), r.Belt,
+			dyson.FormatNumber(r.OutputRate)),
+		Data: Data{
+			Version:          1,
+			CursorOffsetX:    int32(length / 2),
+			CursorOffsetY:    buildingY,
+			CursorTargetArea: 0,
+			DragBoxSizeX:     int32(length + 1),
+			DragBoxSizeY:     outputY + 1,
+			PrimaryAreaIdx:   0,
+			Areas: []Area{{
+				Index:        0,
+				ParentIndex:  -1,
+				AreaSegments: 200,
+				Width:        int16(length + 1),
+				Height:       outputY + 1,
+			}},
+			Buildings: buildings,
+		},
+	}
+}
+
+// String summarises the row, e.g. "4 Arc Smelter making Iron Ingot: Conveyor Belt Mk. I, 1 Sorter Mk. I in and 1
+// out per building"
+func (r *Row) String() string {
+	s := fmt.Sprintf("%d %s making %s: %s, %d %s in and %d out per building", r.Count, r.Building, r.Target, r.Belt,
+		r.InputSorters, r.Sorter, r.OutputSorters)
+	if r.RecipeID == 0 {
+		s += " (recipe not set: the data has no recipe ID)"
+	}
+	return s
+}
@@ -0,0 +1,156 @@
+package blueprint
+
+import (
+	"github.com/ghjm/dyson/pkg/dyson"
+	"strings"
+	"testing"
+)
+
+const rowTestYAMLData = `
+facilities:
+  smelter:
+    Arc Smelter: 1
+    Plane Smelter: 8
+  assembler:
+    Assembling Machine Mk. I: 0.75
+    Assembling Machine Mk. II: 1
+  mine:
+    Mining Machine: 1
+processes:
+  - makes: { Iron Ore: 1 }
+    time: 1
+    facility: [ mine ]
+  - makes: { Iron Ingot: 1 }
+    consumes: { Iron Ore: 1 }
+    time: 1
+    facility: [ smelter ]
+    id: 1
+  - makes: { Gear: 1 }
+    consumes: { Iron Ingot: 1 }
+    time: 1
+    facility: [ assembler ]
+    id: 5
+  - makes: { Gear: 2 }
+    consumes: { Iron Ingot: 1, Iron Ore: 1 }
+    time: 1
+    facility: [ assembler ]
+    special: true
+    id: 7
+`
+
+func loadRowTestData(t *testing.T) *dyson.DataFile {
+	df, err := dyson.LoadData([]byte(rowTestYAMLData))
+	if err != nil {
+		t.Fatalf("Failed to load test data: %v", err)
+	}
+	return df
+}
+
+func rowTestStep(t *testing.T, target string, rate float64, opts ...dyson.ChainOption) *dyson.ProductionStep {
+	pc := loadRowTestData(t).NewChain([]string{target}, opts...)
+	err := pc.SetRate(target, rate)
+	if err != nil {
+		t.Fatalf("Failed to set rate: %v", err)
+	}
+	err = pc.FillChain()
+	if err != nil {
+		t.Fatalf("Failed to fill chain: %v", err)
+	}
+	return &pc.Steps[0]
+}
+
+func TestNewRow(t *testing.T) {
+	tests := []struct {
+		target  string
+		rate    float64
+		want    string
+		wantErr string
+	}{
+		{target: "Gear", rate: 2, want: "2 Assembling Machine Mk. II making Gear: Conveyor Belt Mk. I, " +
+			"1 Sorter Mk. I in and 1 out per building"},
+		{target: "Gear", rate: 2.5, want: "3 Assembling Machine Mk. II making Gear: Conveyor Belt Mk. I, " +
+			"1 Sorter Mk. I in and 1 out per building"},
+		{target: "Iron Ingot", rate: 10, want: "10 Arc Smelter making Iron Ingot: Conveyor Belt Mk. II, " +
+			"1 Sorter Mk. I in and 1 out per building"},
+		{target: "Iron Ingot", rate: 40, wantErr: "more than the fastest belt carries"},
+		{target: "Iron Ore", rate: 1, wantErr: "only smelter and assembler rows"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			r, err := NewRow(rowTestStep(t, tt.target, tt.rate))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("NewRow() error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Failed to make row: %v", err)
+			}
+			if r.String() != tt.want {
+				t.Errorf("NewRow() = %q, want %q", r, tt.want)
+			}
+		})
+	}
+}
+
+func TestRow_SorterTiers(t *testing.T) {
+	// Each of these fast smelters moves 8/s in and out, too much for three Mk. I sorters but not for three Mk. II
+	r, err := NewRow(rowTestStep(t, "Iron Ingot", 24, dyson.WithBuilding("smelter", "Plane Smelter")))
+	if err != nil {
+		t.Fatalf("Failed to make row: %v", err)
+	}
+	want := "3 Plane Smelter making Iron Ingot: Conveyor Belt Mk. III, 3 Sorter Mk. II in and 3 out per building"
+	if r.String() != want {
+		t.Errorf("NewRow() = %q, want %q", r, want)
+	}
+}
+
+func TestRow_Blueprint(t *testing.T) {
+	r, err := NewRow(rowTestStep(t, "Gear", 2))
+	if err != nil {
+		t.Fatalf("Failed to make row: %v", err)
+	}
+	s, err := r.Blueprint().Encode()
+	if err != nil {
+		t.Fatalf("Failed to encode blueprint: %v", err)
+	}
+	bp, err := Parse(s)
+	if err != nil {
+		t.Fatalf("Failed to parse blueprint: %v", err)
+	}
+	if bp.Icons[0] != 2304 || bp.ShortDesc != "2 Assembling Machine Mk. II: Gear" {
+		t.Errorf("blueprint header = %v %q", bp.Icons, bp.ShortDesc)
+	}
+
+	counts := make(map[int16]int)
+	byIndex := make(map[int32]Building)
+	for _, b := range bp.Data.Buildings {
+		counts[b.ItemID]++
+		byIndex[b.Index] = b
+	}
+	// Two belts of 7 cells, 2 assemblers and 4 sorters
+	if counts[2001] != 14 || counts[2304] != 2 || counts[2011] != 4 || len(counts) != 3 {
+		t.Errorf("blueprint buildings = %v", counts)
+	}
+	for _, b := range bp.Data.Buildings {
+		switch b.ItemID {
+		case 2304:
+			if b.RecipeID != 5 {
+				t.Errorf("assembler %d has recipe %d, want 5", b.Index, b.RecipeID)
+			}
+		case 2011:
+			from, to := byIndex[b.InputObjIdx], byIndex[b.OutputObjIdx]
+			if !(from.ItemID == 2001 && to.ItemID == 2304) && !(from.ItemID == 2304 && to.ItemID == 2001) {
+				t.Errorf("sorter %d takes from %d and puts onto %d", b.Index, from.ItemID, to.ItemID)
+			}
+			if from.LocalOffset[0] != b.LocalOffset[0] && to.LocalOffset[0] != b.LocalOffset2[0] {
+				t.Errorf("sorter %d is not next to its belt", b.Index)
+			}
+		case 2001:
+			if b.OutputObjIdx >= 0 && byIndex[b.OutputObjIdx].LocalOffset[0] != b.LocalOffset[0]+1 {
+				t.Errorf("belt %d does not lead to the next cell", b.Index)
+			}
+		}
+	}
+}
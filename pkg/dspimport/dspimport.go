@@ -0,0 +1,292 @@
+// Package dspimport converts game data extracted from Dyson Sphere Program into the dyson data file format.
+//
+// The input is a JSON dump of the game's item and recipe prototype sets, as written by the common data-extraction
+// mods:
+//
+//	{
+//	  "ItemProtoSet": { "dataArray": [
+//	    { "ID": 1001, "Name": "Iron Ore", "Type": "Resource", "StackSize": 100, "HeatValue": 0, ... }
+//	  ] },
+//	  "RecipeProtoSet": { "dataArray": [
+//	    { "ID": 1, "Name": "Iron Ingot", "Type": "Smelt", "Handcraft": true, "Explicit": false, "NonProductive": false,
+//	      "TimeSpend": 60, "Items": [1001], "ItemCounts": [1], "Results": [1101], "ResultCounts": [1] }
+//	  ] }
+//	}
+//
+// Enumerated types may be given either by name or by their numeric value in the game.  Other fields are ignored.
+package dspimport
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/ghjm/dyson/pkg/dyson"
+	"slices"
+	"strconv"
+)
+
+// ticksPerSecond is the game's simulation rate, which recipe times are given in
+const ticksPerSecond = 60
+
+// Dump is a JSON dump of the game's prototype sets
+type Dump struct {
+	ItemProtoSet   ProtoSet[ItemProto]   `json:"ItemProtoSet"`
+	RecipeProtoSet ProtoSet[RecipeProto] `json:"RecipeProtoSet"`
+}
+
+type ProtoSet[T any] struct {
+	DataArray []T `json:"dataArray"`
+}
+
+type ItemProto struct {
+	ID        int      `json:"ID"`
+	Name      string   `json:"Name"`
+	Type      EnumName `json:"Type"`
+	StackSize int      `json:"StackSize"`
+	HeatValue int64    `json:"HeatValue"` // in joules
+}
+
+type RecipeProto struct {
+	ID            int      `json:"ID"`
+	Name          string   `json:"Name"`
+	Type          EnumName `json:"Type"`
+	Handcraft     bool     `json:"Handcraft"`
+	Explicit      bool     `json:"Explicit"`
+	NonProductive bool     `json:"NonProductive"`
+	TimeSpend     int      `json:"TimeSpend"`
+	Items         []int    `json:"Items"`
+	ItemCounts    []int    `json:"ItemCounts"`
+	Results       []int    `json:"Results"`
+	ResultCounts  []int    `json:"ResultCounts"`
+}
+
+// EnumName is a game enum value, which dumps write either as its name or its number.  Numbers are stored as their
+// decimal string.
+type EnumName string
+
+func (e *EnumName) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*e = EnumName(s)
+		return nil
+	}
+	var n int
+	if err := json.Unmarshal(data, &n); err != nil {
+		return fmt.Errorf("enum value must be a string or number: %s", data)
+	}
+	*e = EnumName(strconv.Itoa(n))
+	return nil
+}
+
+// recipeFacilities maps the game's ERecipeType, by name and number, to dyson facility types
+var recipeFacilities = map[EnumName]string{
+	"Smelt":       "smelter",
+	"1":           "smelter",
+	"Chemical":    "chemical",
+	"2":           "chemical",
+	"Refine":      "refinery",
+	"3":           "refinery",
+	"Assemble":    "assembler",
+	"4":           "assembler",
+	"Particle":    "particle",
+	"5":           "particle",
+	"Exchange":    "energy",
+	"6":           "energy",
+	"PhotonStore": "ray",
+	"7":           "ray",
+	"Fractionate": "fractionator",
+	"8":           "fractionator",
+	"Research":    "science",
+	"15":          "science",
+}
+
+// itemCategories maps the game's EItemType, by name and number, to dyson item categories.  Products that burn are
+// fuel rather than components.
+var itemCategories = map[EnumName]dyson.ItemCategory{
+	"Resource":   dyson.CategoryResource,
+	"1":          dyson.CategoryResource,
+	"Material":   dyson.CategoryComponent,
+	"2":          dyson.CategoryComponent,
+	"Component":  dyson.CategoryComponent,
+	"3":          dyson.CategoryComponent,
+	"Product":    dyson.CategoryComponent,
+	"4":          dyson.CategoryComponent,
+	"Logistics":  dyson.CategoryBuilding,
+	"5":          dyson.CategoryBuilding,
+	"Production": dyson.CategoryBuilding,
+	"6":          dyson.CategoryBuilding,
+	"Decoration": dyson.CategoryBuilding,
+	"7":          dyson.CategoryBuilding,
+	"Turret":     dyson.CategoryCombat,
+	"8":          dyson.CategoryCombat,
+	"Defense":    dyson.CategoryCombat,
+	"9":          dyson.CategoryCombat,
+	"DarkFog":    dyson.CategoryCombat,
+	"10":         dyson.CategoryCombat,
+	"Matrix":     dyson.CategoryMatrix,
+	"11":         dyson.CategoryMatrix,
+}
+
+// resourceSource describes how a raw resource is gathered
+type resourceSource struct {
+	facility []string
+	time     float64
+}
+
+// resourceSources lists raw resources that are not mined.  Anything else is assumed to come from a mine.
+var resourceSources = map[string]resourceSource{
+	"Water":           {facility: []string{"pump"}, time: 1.2},
+	"Sulfuric Acid":   {facility: []string{"pump"}, time: 1.2},
+	"Crude Oil":       {facility: []string{"extractor"}, time: 1},
+	"Hydrogen":        {facility: []string{"collector"}, time: 1},
+	"Deuterium":       {facility: []string{"collector"}, time: 1},
+	"Fire Ice":        {facility: []string{"mine", "collector"}, time: 2},
+	"Critical Photon": {facility: []string{"ray"}, time: 1},
+}
+
+var mineSource = resourceSource{facility: []string{"mine"}, time: 2}
+
+// Parse reads a JSON prototype dump
+func Parse(data []byte) (*Dump, error) {
+	var d Dump
+	err := json.Unmarshal(data, &d)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse prototype dump: %w", err)
+	}
+	if len(d.ItemProtoSet.DataArray) == 0 {
+		return nil, fmt.Errorf("prototype dump contains no items")
+	}
+	return &d, nil
+}
+
+// Processes converts the dump's recipes into dyson processes.  Raw resources, which are items of type Resource and
+// anything consumed but never produced, get a gathering process first.  A recipe is special if the game marks it
+// as an explicit (alternative) recipe, or if an earlier recipe already makes its main product.
+func (d *Dump) Processes() ([]dyson.Process, error) {
+	names := make(map[int]string)
+	resources := make(map[int]struct{})
+	for _, item := range d.ItemProtoSet.DataArray {
+		if _, ok := names[item.ID]; ok {
+			return nil, fmt.Errorf("duplicate item ID %d", item.ID)
+		}
+		names[item.ID] = item.Name
+		if item.Type == "Resource" || item.Type == "1" {
+			resources[item.ID] = struct{}{}
+		}
+	}
+
+	recipes := slices.Clone(d.RecipeProtoSet.DataArray)
+	slices.SortFunc(recipes, func(a, b RecipeProto) int { return a.ID - b.ID })
+
+	produced := make(map[int]struct{})
+	consumed := make(map[int]struct{})
+	var recipeProcs []dyson.Process
+	for _, r := range recipes {
+		facility, ok := recipeFacilities[r.Type]
+		if !ok {
+			return nil, fmt.Errorf("recipe %d (%s): unknown recipe type %s", r.ID, r.Name, r.Type)
+		}
+		if len(r.Items) != len(r.ItemCounts) || len(r.Results) != len(r.ResultCounts) {
+			return nil, fmt.Errorf("recipe %d (%s): item and count lists differ in length", r.ID, r.Name)
+		}
+		if len(r.Results) == 0 {
+			return nil, fmt.Errorf("recipe %d (%s): no results", r.ID, r.Name)
+		}
+		proc := dyson.Process{
+			Makes:    make(map[string]int),
+			Consumes: make(map[string]int),
+			Time:     float64(r.TimeSpend) / ticksPerSecond,
+			Facility: []string{facility},
+			Special:  r.Explicit,
+			ID:       r.ID,
+
+			NonProductive: r.NonProductive,
+		}
+		if r.Handcraft {
+			proc.Facility = append(proc.Facility, "replicator")
+		}
+		for i, id := range r.Results {
+			name, ok := names[id]
+			if !ok {
+				return nil, fmt.Errorf("recipe %d (%s): unknown item ID %d", r.ID, r.Name, id)
+			}
+			proc.Makes[name] += r.ResultCounts[i]
+		}
+		for i, id := range r.Items {
+			name, ok := names[id]
+			if !ok {
+				return nil, fmt.Errorf("recipe %d (%s): unknown item ID %d", r.ID, r.Name, id)
+			}
+			proc.Consumes[name] += r.ItemCounts[i]
+			consumed[id] = struct{}{}
+		}
+		if _, ok := produced[r.Results[0]]; ok {
+			proc.Special = true
+		}
+		if !proc.Special {
+			produced[r.Results[0]] = struct{}{}
+		}
+		recipeProcs = append(recipeProcs, proc)
+	}
+	for _, r := range recipes {
+		for _, id := range r.Results {
+			produced[id] = struct{}{}
+		}
+	}
+
+	var procs []dyson.Process
+	for _, item := range d.ItemProtoSet.DataArray {
+		_, isResource := resources[item.ID]
+		_, isConsumed := consumed[item.ID]
+		_, isProduced := produced[item.ID]
+		if !isResource && (!isConsumed || isProduced) {
+			continue
+		}
+		src, ok := resourceSources[item.Name]
+		if !ok {
+			src = mineSource
+		}
+		procs = append(procs, dyson.Process{
+			Makes:    map[string]int{item.Name: 1},
+			Time:     src.time,
+			Facility: src.facility,
+		})
+	}
+	return append(procs, recipeProcs...), nil
+}
+
+// Items converts the dump's items into dyson item metadata.  Items of unknown type are left out.
+func (d *Dump) Items() map[string]dyson.Item {
+	items := make(map[string]dyson.Item)
+	for _, ip := range d.ItemProtoSet.DataArray {
+		cat, ok := itemCategories[ip.Type]
+		if !ok {
+			continue
+		}
+		item := dyson.Item{
+			Category: cat,
+			Stack:    ip.StackSize,
+			Fuel:     float64(ip.HeatValue) / 1e6,
+		}
+		if cat == dyson.CategoryComponent && (ip.Type == "Product" || ip.Type == "4") && ip.HeatValue > 0 {
+			item.Category = dyson.CategoryFuel
+		}
+		items[ip.Name] = item
+	}
+	return items
+}
+
+// Convert turns a JSON prototype dump into a data file, using the given facilities since the dump does not
+// describe building speeds
+func Convert(data []byte, facilities map[string]map[string]float64) (*dyson.DataFile, error) {
+	d, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	procs, err := d.Processes()
+	if err != nil {
+		return nil, err
+	}
+	df := dyson.NewDataFile(facilities, procs)
+	df.Items = d.Items()
+	return df, nil
+}
@@ -0,0 +1,243 @@
+package dspimport
+
+import (
+	"flag"
+	"os"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+var testFacilities = map[string]map[string]float64{
+	"replicator":   {"Mecha": 1},
+	"smelter":      {"Arc Smelter": 1},
+	"assembler":    {"Assembling Machine Mk. II": 1},
+	"refinery":     {"Oil Refinery": 1},
+	"science":      {"Matrix Lab": 1},
+	"particle":     {"Miniature Particle Collider": 1},
+	"mine":         {"Mining Machine": 1},
+	"pump":         {"Water Pump": 1},
+	"extractor":    {"Oil Extractor": 1},
+	"collector":    {"Orbital Collector": 1},
+	"ray":          {"Ray Receiver": 1},
+	"fractionator": {"Fractionator": 1},
+}
+
+func readSample(t *testing.T) []byte {
+	data, err := os.ReadFile("testdata/sample.json")
+	if err != nil {
+		t.Fatalf("Failed to read sample dump: %v", err)
+	}
+	return data
+}
+
+func TestConvert_Golden(t *testing.T) {
+	df, err := Convert(readSample(t), testFacilities)
+	if err != nil {
+		t.Fatalf("Convert() failed: %v", err)
+	}
+	err = df.Validate()
+	if err != nil {
+		t.Errorf("Converted data does not validate: %v", err)
+	}
+	got, err := df.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal() failed: %v", err)
+	}
+	if *update {
+		err = os.WriteFile("testdata/sample.yml", got, 0o644)
+		if err != nil {
+			t.Fatalf("Failed to update golden file: %v", err)
+		}
+	}
+	want, err := os.ReadFile("testdata/sample.yml")
+	if err != nil {
+		t.Fatalf("Failed to read golden file: %v", err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("Convert() output differs from testdata/sample.yml (run with -update to regenerate):\n%s", got)
+	}
+}
+
+func TestDump_Processes(t *testing.T) {
+	d, err := Parse(readSample(t))
+	if err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+	procs, err := d.Processes()
+	if err != nil {
+		t.Fatalf("Processes() failed: %v", err)
+	}
+
+	byName := func(item string, input string) int {
+		for i, p := range procs {
+			if _, ok := p.Makes[item]; !ok {
+				continue
+			}
+			if _, ok := p.Consumes[input]; ok || (input == "" && len(p.Consumes) == 0) {
+				return i
+			}
+		}
+		t.Fatalf("no process makes %s from %q", item, input)
+		return -1
+	}
+
+	tests := []struct {
+		name     string
+		item     string
+		input    string
+		time     float64
+		facility string
+		special  bool
+		nonprod  bool
+	}{
+		{name: "mined resource", item: "Iron Ore", time: 2, facility: "mine"},
+		{name: "numeric resource type", item: "Crude Oil", time: 1, facility: "extractor"},
+		{name: "pumped resource", item: "Water", time: 1.2, facility: "pump"},
+		{name: "consumed but never produced", item: "Critical Photon", time: 1, facility: "ray"},
+		{name: "handcraftable recipe", item: "Iron Ingot", input: "Iron Ore", time: 1, facility: "smelter,replicator"},
+		{name: "numeric recipe type", item: "Copper Ingot", input: "Copper Ore", time: 1,
+			facility: "smelter,replicator"},
+		{name: "research recipe", item: "Electromagnetic Matrix", input: "Circuit Board", time: 3, facility: "science"},
+		{name: "explicit recipe", item: "Hydrogen", input: "Refined Oil", time: 4, facility: "refinery", special: true},
+		{name: "second recipe for a product", item: "Refined Oil", input: "Coal", time: 4, facility: "refinery",
+			special: true},
+		{name: "main recipe", item: "Refined Oil", input: "Crude Oil", time: 4, facility: "refinery"},
+		{name: "non-productive recipe", item: "Conveyor Belt Mk. I", input: "Gear", time: 1,
+			facility: "assembler,replicator", nonprod: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := procs[byName(tt.item, tt.input)]
+			if p.Time != tt.time {
+				t.Errorf("time = %v, want %v", p.Time, tt.time)
+			}
+			if strings.Join(p.Facility, ",") != tt.facility {
+				t.Errorf("facility = %v, want %v", p.Facility, tt.facility)
+			}
+			if p.Special != tt.special {
+				t.Errorf("special = %v, want %v", p.Special, tt.special)
+			}
+			if p.NonProductive != tt.nonprod {
+				t.Errorf("non-productive = %v, want %v", p.NonProductive, tt.nonprod)
+			}
+		})
+	}
+
+	// Hydrogen is produced by recipes, so it doesn't get a gathering process
+	for _, p := range procs {
+		if _, ok := p.Makes["Hydrogen"]; ok && len(p.Consumes) == 0 {
+			t.Error("Hydrogen should not have a gathering process")
+		}
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		errMsg string
+	}{
+		{
+			name:   "invalid JSON",
+			data:   "{",
+			errMsg: "could not parse",
+		},
+		{
+			name:   "no items",
+			data:   `{"ItemProtoSet": {"dataArray": []}}`,
+			errMsg: "no items",
+		},
+		{
+			name:   "bad enum",
+			data:   `{"ItemProtoSet": {"dataArray": [{"ID": 1, "Name": "A", "Type": true}]}}`,
+			errMsg: "string or number",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Parse() error = %v, want error containing %q", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestDump_ProcessesErrors(t *testing.T) {
+	items := `"ItemProtoSet": {"dataArray": [{"ID": 1, "Name": "A", "Type": "Resource"}, {"ID": 2, "Name": "B"}]}`
+	tests := []struct {
+		name    string
+		recipes string
+		errMsg  string
+	}{
+		{
+			name:    "unknown recipe type",
+			recipes: `{"ID": 1, "Name": "B", "Type": "Juggle", "Items": [1], "ItemCounts": [1], "Results": [2], "ResultCounts": [1]}`,
+			errMsg:  "unknown recipe type Juggle",
+		},
+		{
+			name:    "unknown item",
+			recipes: `{"ID": 1, "Name": "B", "Type": "Smelt", "Items": [3], "ItemCounts": [1], "Results": [2], "ResultCounts": [1]}`,
+			errMsg:  "unknown item ID 3",
+		},
+		{
+			name:    "mismatched counts",
+			recipes: `{"ID": 1, "Name": "B", "Type": "Smelt", "Items": [1], "ItemCounts": [], "Results": [2], "ResultCounts": [1]}`,
+			errMsg:  "differ in length",
+		},
+		{
+			name:    "no results",
+			recipes: `{"ID": 1, "Name": "B", "Type": "Smelt", "Items": [1], "ItemCounts": [1]}`,
+			errMsg:  "no results",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := Parse([]byte(`{` + items + `, "RecipeProtoSet": {"dataArray": [` + tt.recipes + `]}}`))
+			if err != nil {
+				t.Fatalf("Parse() failed: %v", err)
+			}
+			_, err = d.Processes()
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Processes() error = %v, want error containing %q", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestDump_Items(t *testing.T) {
+	d, err := Parse([]byte(`{"ItemProtoSet": {"dataArray": [
+		{"ID": 1, "Name": "Coal", "Type": "Resource", "StackSize": 100, "HeatValue": 2700000},
+		{"ID": 2, "Name": "Hydrogen Fuel Rod", "Type": 4, "StackSize": 30, "HeatValue": 54000000},
+		{"ID": 3, "Name": "Small Carrier Rocket", "Type": "Product", "StackSize": 20},
+		{"ID": 4, "Name": "Tesla Tower", "Type": "Logistics", "StackSize": 50},
+		{"ID": 5, "Name": "Mystery", "Type": 99}
+	]}}`))
+	if err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+	items := d.Items()
+	tests := []struct {
+		name     string
+		category string
+		stack    int
+		fuel     float64
+	}{
+		{name: "Coal", category: "resource", stack: 100, fuel: 2.7},
+		{name: "Hydrogen Fuel Rod", category: "fuel", stack: 30, fuel: 54},
+		{name: "Small Carrier Rocket", category: "component", stack: 20},
+		{name: "Tesla Tower", category: "building", stack: 50},
+	}
+	for _, tt := range tests {
+		item, ok := items[tt.name]
+		if !ok || string(item.Category) != tt.category || item.Stack != tt.stack || item.Fuel != tt.fuel {
+			t.Errorf("%s = %+v, want category %s, stack %d, fuel %v", tt.name, item, tt.category, tt.stack, tt.fuel)
+		}
+	}
+	if _, ok := items["Mystery"]; ok {
+		t.Error("items of unknown type should be left out")
+	}
+}
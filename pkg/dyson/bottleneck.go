@@ -0,0 +1,152 @@
+package dyson
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+	"text/tabwriter"
+)
+
+// StepCapacity is how much one step of an existing factory can make with the buildings placed for it
+type StepCapacity struct {
+	Item     string
+	Building string
+	Built    float64 // buildings placed for the step
+	Needed   float64 // buildings needed for the planned rate
+	MaxRate  float64 // most the placed buildings can make, in items per second
+	Limit    float64 // fraction of the planned output the step allows
+}
+
+// BottleneckAnalysis shows what an existing factory can actually produce, and what holds it back
+type BottleneckAnalysis struct {
+	Scale       float64            // achievable fraction of the planned target rates
+	Targets     map[string]float64 // achievable rate of each target
+	Steps       []StepCapacity     // steps with buildings placed, in chain order
+	Unbuilt     []string           // steps with no building count, which are assumed to keep up
+	Bottlenecks []string           // steps that limit the output
+	NextScale   float64            // output reached by relieving the bottlenecks, or 0 if every step is one
+	Extra       map[string]int     // buildings to add to each step to reach NextScale
+}
+
+// limitTolerance is how close two steps' limits must be for both to count as bottlenecks
+const limitTolerance = 1e-4
+
+// Bottlenecks works out how much of a plan's target rates the buildings listed in its built section can achieve.
+// The targets keep the proportions of their planned rates.  Steps with no building count are assumed to keep up.
+func (df *DataFile) Bottlenecks(p *Plan) (*BottleneckAnalysis, error) {
+	if len(p.Built) == 0 {
+		return nil, fmt.Errorf("plan has no built buildings")
+	}
+	pc, err := df.RunPlan(p)
+	if err != nil {
+		return nil, err
+	}
+
+	ba := &BottleneckAnalysis{Extra: make(map[string]int)}
+	inChain := make(map[string]struct{})
+	for _, step := range pc.Steps {
+		inChain[step.Target] = struct{}{}
+		if step.Process == nil || step.Rate <= 0 {
+			continue
+		}
+		built, ok := p.Built[step.Target]
+		if !ok {
+			ba.Unbuilt = append(ba.Unbuilt, step.Target)
+			continue
+		}
+		maxRate, err := pc.FactoriesToItemsPerSecond(step.Target, built)
+		if err != nil {
+			return nil, err
+		}
+		ba.Steps = append(ba.Steps, StepCapacity{
+			Item:     step.Target,
+			Building: step.Building,
+			Built:    built,
+			Needed:   step.Factories(),
+			MaxRate:  maxRate,
+			Limit:    maxRate / step.Rate,
+		})
+	}
+	for _, item := range slices.Sorted(maps.Keys(p.Built)) {
+		if _, ok := inChain[item]; !ok {
+			return nil, fmt.Errorf("%s is built but not part of the chain", item)
+		}
+	}
+	if len(ba.Steps) == 0 {
+		return nil, fmt.Errorf("no built step is used by the plan")
+	}
+
+	ba.Scale = slices.MinFunc(ba.Steps, func(a, b StepCapacity) int {
+		return cmp.Compare(a.Limit, b.Limit)
+	}).Limit
+	for _, s := range ba.Steps {
+		if s.Limit <= ba.Scale*(1+limitTolerance) {
+			ba.Bottlenecks = append(ba.Bottlenecks, s.Item)
+		} else if ba.NextScale == 0 || s.Limit < ba.NextScale {
+			ba.NextScale = s.Limit
+		}
+	}
+	if ba.NextScale > 0 {
+		for _, s := range ba.Steps {
+			if s.Limit < ba.NextScale*(1-limitTolerance) {
+				extra := math.Ceil(s.Needed*ba.NextScale - s.Built - limitTolerance)
+				ba.Extra[s.Item] = int(extra)
+			}
+		}
+	}
+
+	ba.Targets = make(map[string]float64)
+	for item, rate := range pc.requested {
+		ba.Targets[item] = rate * ba.Scale
+	}
+	return ba, nil
+}
+
+// String formats the analysis for display
+func (ba *BottleneckAnalysis) String() string {
+	return ba.StringWithOpts()
+}
+
+// StringWithOpts formats the analysis for display with the given options
+func (ba *BottleneckAnalysis) StringWithOpts(opts ...StringOption) string {
+	so := newStringOptions(opts)
+	percent := func(f float64) string {
+		return so.number(f*100) + "%"
+	}
+	sb := strings.Builder{}
+	sb.WriteString(fmt.Sprintf("Achievable output: %s of plan\n", percent(ba.Scale)))
+	for _, item := range slices.Sorted(maps.Keys(ba.Targets)) {
+		sb.WriteString(fmt.Sprintf("  %s: %s\n", item, so.rate(ba.Targets[item])))
+	}
+
+	sb.WriteString("\n")
+	tw := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Step\tBuilt\tNeeded\tMax rate\tLimit")
+	for _, s := range ba.Steps {
+		limit := percent(s.Limit)
+		if slices.Contains(ba.Bottlenecks, s.Item) {
+			limit += " (bottleneck)"
+		}
+		fmt.Fprintf(tw, "%s\t%s %s\t%s\t%s\t%s\n", s.Item, so.number(s.Built), s.Building,
+			so.number(s.Needed), so.rate(s.MaxRate), limit)
+	}
+	_ = tw.Flush()
+
+	if ba.NextScale > 0 {
+		sb.WriteString(fmt.Sprintf("\nTo reach %s of plan, add:\n", percent(ba.NextScale)))
+		for _, s := range ba.Steps {
+			if n, ok := ba.Extra[s.Item]; ok {
+				sb.WriteString(fmt.Sprintf("  %d %s for %s\n", n, s.Building, s.Item))
+			}
+		}
+	} else {
+		sb.WriteString("\nEvery step is a bottleneck, so they all need more buildings to raise the output\n")
+	}
+	if len(ba.Unbuilt) > 0 {
+		sb.WriteString(fmt.Sprintf("\nAssumed to keep up: %s\n", strings.Join(ba.Unbuilt, ", ")))
+	}
+	return sb.String()
+}
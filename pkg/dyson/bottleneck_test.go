@@ -0,0 +1,91 @@
+package dyson
+
+import (
+	"maps"
+	"strings"
+	"testing"
+)
+
+func TestDataFile_Bottlenecks(t *testing.T) {
+	df, err := LoadData([]byte(testYAMLData))
+	if err != nil {
+		t.Fatalf("Failed to load test data: %v", err)
+	}
+
+	// At 2 Circuit Boards and 1 Gear per second, the chain needs 1 assembler for boards, 1 for gears, 3 Iron Ingot
+	// smelters and 1 Copper Ingot smelter
+	targets := []PlanTarget{{Item: "Circuit Board", Rate: 2}, {Item: "Gear", Rate: 1}}
+	tests := []struct {
+		name        string
+		built       map[string]float64
+		scale       float64
+		bottlenecks []string
+		nextScale   float64
+		extra       map[string]int
+		errMsg      string
+	}{
+		{
+			name:        "one bottleneck",
+			built:       map[string]float64{"Circuit Board": 1, "Gear": 1, "Iron Ingot": 2, "Copper Ingot": 1},
+			scale:       2.0 / 3,
+			bottlenecks: []string{"Iron Ingot"},
+			nextScale:   1,
+			extra:       map[string]int{"Iron Ingot": 1},
+		},
+		{
+			name:        "several levels",
+			built:       map[string]float64{"Circuit Board": 0.5, "Gear": 2, "Iron Ingot": 1, "Copper Ingot": 1},
+			scale:       1.0 / 3,
+			bottlenecks: []string{"Iron Ingot"},
+			nextScale:   0.5,
+			extra:       map[string]int{"Iron Ingot": 1},
+		},
+		{
+			name:        "balanced factory",
+			built:       map[string]float64{"Circuit Board": 2, "Gear": 2, "Iron Ingot": 6, "Copper Ingot": 2},
+			scale:       2,
+			bottlenecks: []string{"Circuit Board", "Gear", "Copper Ingot", "Iron Ingot"},
+			extra:       map[string]int{},
+		},
+		{
+			name:   "no buildings",
+			errMsg: "plan has no built buildings",
+		},
+		{
+			name:   "building outside the chain",
+			built:  map[string]float64{"Gear": 1, "Copper Ore": 1},
+			errMsg: "Copper Ore is built but not part of the chain",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Plan{Targets: targets, Have: []string{"Iron Ore", "Copper Ore"}, Built: tt.built}
+			ba, err := df.Bottlenecks(p)
+			if tt.errMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+					t.Errorf("Bottlenecks() error = %v, want error containing %q", err, tt.errMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Bottlenecks() failed: %v", err)
+			}
+			if FormatNumber(ba.Scale) != FormatNumber(tt.scale) {
+				t.Errorf("Scale = %v, want %v", ba.Scale, tt.scale)
+			}
+			if strings.Join(ba.Bottlenecks, ",") != strings.Join(tt.bottlenecks, ",") {
+				t.Errorf("Bottlenecks = %v, want %v", ba.Bottlenecks, tt.bottlenecks)
+			}
+			if FormatNumber(ba.NextScale) != FormatNumber(tt.nextScale) {
+				t.Errorf("NextScale = %v, want %v", ba.NextScale, tt.nextScale)
+			}
+			if !maps.Equal(ba.Extra, tt.extra) {
+				t.Errorf("Extra = %v, want %v", ba.Extra, tt.extra)
+			}
+			if FormatNumber(ba.Targets["Gear"]) != FormatNumber(tt.scale) {
+				t.Errorf("Gear rate = %v, want %v", ba.Targets["Gear"], tt.scale)
+			}
+		})
+	}
+}
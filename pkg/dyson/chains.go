@@ -3,48 +3,164 @@ package dyson
 import (
 	"fmt"
 	"maps"
+	"math"
 	"slices"
+	"strconv"
 	"strings"
 )
 
 type ProductionChain struct {
-	df    *DataFile
-	Steps []ProductionStep
+	df           *DataFile
+	Steps        []ProductionStep
+	recipes      map[string][]string
+	buildings    map[string]string
+	proliferator Proliferator
+	requested    map[string]float64  // rates set for the chain's targets
+	excluded     map[string]struct{} // items that are never made or used
+	supplied     map[string]struct{} // items supplied from outside the chain, which it uses but doesn't make
 }
 
 type ProductionStep struct {
-	Target  string
-	Process *Process
-	Rate    float32
+	Target   string
+	Process  *Process
+	Rate     float64
+	Facility string // facility type used for this step, e.g. "smelter"
+	Building string // building used for this step, e.g. "Arc Smelter"
+
+	perFactory float64
+}
+
+type ChainOption func(*ProductionChain)
+
+// Proliferator describes how the inputs of a chain are sprayed.  Level 0 means no proliferator is used.
+type Proliferator struct {
+	Level int              `yaml:"level"`
+	Mode  ProliferatorMode `yaml:"mode"`
+}
+
+type ProliferatorMode string
+
+const (
+	ProliferatorExtraProducts ProliferatorMode = "extra"
+	ProliferatorSpeedup       ProliferatorMode = "speed"
+)
+
+var proliferatorLevels = []struct {
+	item   string
+	extra  float64
+	speed  float64
+	sprays float64
+	power  float64 // extra power drawn by buildings working on sprayed inputs
+}{
+	{},
+	{item: "Proliferator Mk. I", extra: 0.125, speed: 0.25, sprays: 12, power: 0.3},
+	{item: "Proliferator Mk. II", extra: 0.2, speed: 0.5, sprays: 24, power: 0.7},
+	{item: "Proliferator Mk. III", extra: 0.25, speed: 1, sprays: 60, power: 1.5},
+}
+
+// Validate checks that the proliferator level and mode are known
+func (p Proliferator) Validate() error {
+	if p.Level < 0 || p.Level >= len(proliferatorLevels) {
+		return fmt.Errorf("invalid proliferator level: %d", p.Level)
+	}
+	if p.Level > 0 && p.Mode != ProliferatorExtraProducts && p.Mode != ProliferatorSpeedup {
+		return fmt.Errorf("invalid proliferator mode: %q", p.Mode)
+	}
+	return nil
+}
+
+// WithRecipe selects the process used to make an item, identified by the exact set of items it consumes.  This
+// allows special processes to be used.
+func WithRecipe(item string, inputs []string) ChainOption {
+	return func(pc *ProductionChain) {
+		pc.recipes[item] = inputs
+	}
+}
+
+// WithBuilding selects which building is used for a facility type, e.g. "Plane Smelter" for "smelter"
+func WithBuilding(facilityType string, building string) ChainOption {
+	return func(pc *ProductionChain) {
+		pc.buildings[facilityType] = building
+	}
+}
+
+// WithExcludedItems keeps items out of the chain entirely: they are never made, and processes that use them are never
+// chosen
+func WithExcludedItems(items ...string) ChainOption {
+	return func(pc *ProductionChain) {
+		for _, item := range items {
+			pc.excluded[item] = struct{}{}
+		}
+	}
+}
+
+// WithProliferator sprays the inputs of every non-raw process with proliferator.  In extra products mode, processes
+// that can't make extra products, such as those making buildings, are left unsprayed.
+func WithProliferator(p Proliferator) ChainOption {
+	return func(pc *ProductionChain) {
+		pc.proliferator = p
+	}
 }
 
 type StringOptions struct {
 	converterFunc StringUnitConverterFunc
+	showBuildings bool
+	categories    []ItemCategory
+	collapse      bool
+	precision     int
+	unit          RateUnit
 }
 
 type StringOption func(*StringOptions)
 
-type StringUnitConverterFunc func(item string, rate float32) (bool, float32, string)
+// DefaultPrecision is the number of decimal places numbers are shown to unless WithPrecision is given
+const DefaultPrecision = 3
+
+// newStringOptions applies string options to the defaults
+func newStringOptions(opts []StringOption) *StringOptions {
+	so := &StringOptions{precision: DefaultPrecision, unit: PerSecond}
+	for _, opt := range opts {
+		opt(so)
+	}
+	return so
+}
+
+type StringUnitConverterFunc func(item string, rate float64) (bool, float64, string)
 
-func (df *DataFile) NewChain(reqs []string) *ProductionChain {
+func (df *DataFile) NewChain(reqs []string, opts ...ChainOption) *ProductionChain {
 	pc := &ProductionChain{
-		df: df,
+		df:        df,
+		recipes:   make(map[string][]string),
+		buildings: make(map[string]string),
+		requested: make(map[string]float64),
+		excluded:  make(map[string]struct{}),
+		supplied:  make(map[string]struct{}),
+	}
+	for _, opt := range opts {
+		opt(pc)
 	}
 	for _, r := range reqs {
 		pc.Steps = append(pc.Steps, ProductionStep{
 			Target: r,
 		})
+		pc.requested[r] = 0
 	}
 	return pc
 }
 
-func (pc *ProductionChain) SetRate(item string, rate float32) error {
+func (pc *ProductionChain) SetRate(item string, rate float64) error {
 	for i := range pc.Steps {
 		if pc.Steps[i].Target == item {
 			pc.Steps[i].Rate = rate
+			if _, ok := pc.requested[item]; ok {
+				pc.requested[item] = rate
+			}
 			return nil
 		}
 	}
+	if !pc.df.HasItem(item) {
+		return &ErrUnknownItem{Item: item}
+	}
 	return fmt.Errorf("item not found in chain: %s", item)
 }
 
@@ -52,67 +168,199 @@ func (pc *ProductionChain) fillOneChain(n int) error {
 	return pc.fillOneChainExcluding(n, nil)
 }
 
-func (pc *ProductionChain) fillOneChainExcluding(n int, excluded map[string]struct{}) error {
+// fillOneChainExcluding chooses the process and building for step n, and adds steps for the inputs it needs that
+// aren't supplied.  Rates are worked out by balanceRates once every step has been filled.
+func (pc *ProductionChain) fillOneChainExcluding(n int, supplied map[string]struct{}) error {
 	ps := &pc.Steps[n]
 	if ps.Process != nil {
-		return fmt.Errorf("chain already filled")
-	}
-	found := false
-	for _, proc := range pc.df.procsByTarget[ps.Target] {
-		if !proc.Special {
-			found = true
-			ps.Process = &proc
-
-			var runsPerSecond float32
-			itemsPerRun := float32(proc.Makes[ps.Target])
-			if itemsPerRun > 0 {
-				runsPerSecond = ps.Rate / itemsPerRun
-			}
-
-			for _, con := range slices.Sorted(maps.Keys(proc.Consumes)) {
-				// Skip excluded items
-				if excluded != nil {
-					if _, isExcluded := excluded[con]; isExcluded {
-						continue
-					}
-				}
+		return errChainFilled
+	}
+	if _, ok := pc.excluded[ps.Target]; ok {
+		return fmt.Errorf("%s is needed but is excluded from the chain", ps.Target)
+	}
+	proc, err := pc.selectProcess(ps.Target)
+	if err != nil {
+		return err
+	}
+	ps.Process = proc
+	ps.Facility, ps.Building, ps.perFactory, err = pc.selectBuilding(ps.Target, proc)
+	if err != nil {
+		return err
+	}
+	for _, con := range slices.Sorted(maps.Keys(proc.Consumes)) {
+		if _, isSupplied := supplied[con]; isSupplied {
+			continue
+		}
+		if !slices.ContainsFunc(pc.Steps, func(step ProductionStep) bool { return step.Target == con }) {
+			pc.Steps = append(pc.Steps, ProductionStep{
+				Target: con,
+			})
+		}
+	}
+	return nil
+}
 
-				consumedAmount := float32(proc.Consumes[con])
-				requiredRate := runsPerSecond * consumedAmount
-
-				alreadyHave := false
-				for i := range pc.Steps {
-					if pc.Steps[i].Target == con {
-						// Add to existing rate (accumulate demand from multiple consumers)
-						pc.Steps[i].Rate += requiredRate
-						alreadyHave = true
-						break
-					}
+// selectProcess returns the process used to make an item: the one chosen with WithRecipe if any, otherwise the
+// first non-special process.
+func (pc *ProductionChain) selectProcess(target string) (*Process, error) {
+	procs := pc.df.procsByTarget[target]
+	if inputs, ok := pc.recipes[target]; ok {
+		for _, proc := range procs {
+			if len(proc.Consumes) != len(inputs) {
+				continue
+			}
+			match := true
+			for _, input := range inputs {
+				if _, ok := proc.Consumes[input]; !ok {
+					match = false
+					break
 				}
-				if !alreadyHave {
-					pc.Steps = append(pc.Steps, ProductionStep{
-						Target: con,
-						Rate:   requiredRate,
-					})
+			}
+			if match {
+				if input, ok := pc.excludedInput(&proc); ok {
+					return nil, fmt.Errorf("the recipe chosen for %s uses %s, which is excluded", target, input)
 				}
+				return &proc, nil
 			}
+		}
+		return nil, &ErrNoRecipe{Item: target, Inputs: inputs}
+	}
+	var excludedInput string
+	for _, proc := range procs {
+		if proc.Special {
+			continue
+		}
+		if input, ok := pc.excludedInput(&proc); ok {
+			excludedInput = input
+			continue
+		}
+		return &proc, nil
+	}
+	if excludedInput != "" {
+		return nil, fmt.Errorf("every process for %s uses an excluded item, such as %s", target, excludedInput)
+	}
+	return nil, pc.df.noProcessError(target)
+}
+
+// excludedInput returns an input of a process that is excluded from the chain, if it has one
+func (pc *ProductionChain) excludedInput(proc *Process) (string, bool) {
+	for _, con := range slices.Sorted(maps.Keys(proc.Consumes)) {
+		if _, ok := pc.excluded[con]; ok {
+			return con, true
+		}
+	}
+	return "", false
+}
+
+// selectBuilding returns the facility type and building used to run a process, and how many of the target item
+// per second a single building produces.  A facility type with a building chosen by WithBuilding is preferred,
+// otherwise the process's first facility type is used with its default building.
+func (pc *ProductionChain) selectBuilding(target string, proc *Process) (string, string, float64, error) {
+	if len(proc.Facility) == 0 {
+		return "", "", 0, nil
+	}
+	facType := proc.Facility[0]
+	for _, ft := range proc.Facility {
+		if _, ok := pc.buildings[ft]; ok {
+			facType = ft
 			break
 		}
 	}
-	if !found {
-		return fmt.Errorf("no processes found for target %s", ps.Target)
+	building, ok := pc.buildings[facType]
+	var speed float64
+	if ok {
+		speed, ok = pc.df.Facilities[facType][building]
+		if !ok {
+			return "", "", 0, fmt.Errorf("unknown building %s for facility type %s", building, facType)
+		}
+	} else {
+		building, speed = pc.df.DefaultBuilding(facType)
+	}
+	var perFactory float64
+	if proc.Time > 0 {
+		perFactory = pc.itemsPerRun(target, proc) / proc.Time * speed
+		if pc.proliferated(proc) && pc.proliferator.Mode == ProliferatorSpeedup {
+			perFactory *= 1 + proliferatorLevels[pc.proliferator.Level].speed
+		}
 	}
-	return nil
+	return facType, building, perFactory, nil
+}
+
+// itemsPerRun returns how many of the target item one run of a process yields, including any extra products
+// from proliferator
+func (pc *ProductionChain) itemsPerRun(target string, proc *Process) float64 {
+	itemsPerRun := float64(proc.Makes[target])
+	if pc.proliferated(proc) && pc.proliferator.Mode == ProliferatorExtraProducts {
+		itemsPerRun *= 1 + proliferatorLevels[pc.proliferator.Level].extra
+	}
+	return itemsPerRun
+}
+
+// maxBalanceRounds limits how many times solveRates recomputes a chain's rates.  A chain without loops settles in as
+// many rounds as it has levels, and a loop that makes more than it uses settles a little more with every round.
+const maxBalanceRounds = 10000
+
+// solveRates works out the rate of every step needed to deliver the given rates of the chain's items.  Each step
+// must make what its consumers use plus what is asked of it directly.  The rates are recomputed from those of the
+// previous round until they stop changing, so a loop of processes that needs an item to make itself is fine as long as
+// the loop makes more of each item than it uses.
+func (pc *ProductionChain) solveRates(demand map[string]float64) ([]float64, error) {
+	index := make(map[string]int, len(pc.Steps))
+	for i, step := range pc.Steps {
+		index[step.Target] = i
+	}
+	rates := make([]float64, len(pc.Steps))
+	unsettled := -1
+	for round := 0; round < maxBalanceRounds; round++ {
+		next := make([]float64, len(pc.Steps))
+		for i, step := range pc.Steps {
+			next[i] = demand[step.Target]
+		}
+		for i, step := range pc.Steps {
+			if step.Process == nil || rates[i] == 0 {
+				continue
+			}
+			itemsPerRun := pc.itemsPerRun(step.Target, step.Process)
+			if itemsPerRun == 0 {
+				continue
+			}
+			runsPerSecond := rates[i] / itemsPerRun
+			for _, con := range slices.Sorted(maps.Keys(step.Process.Consumes)) {
+				if j, ok := index[con]; ok {
+					next[j] += runsPerSecond * float64(step.Process.Consumes[con])
+				}
+			}
+		}
+		unsettled = slices.IndexFunc(pc.Steps, func(step ProductionStep) bool {
+			i := index[step.Target]
+			return !ratesClose(next[i], rates[i])
+		})
+		rates = next
+		if unsettled < 0 {
+			return rates, nil
+		}
+	}
+	return nil, &ErrCycle{Item: pc.Steps[unsettled].Target}
+}
+
+// ratesClose reports whether two rates are equal to within floating point rounding
+func ratesClose(a, b float64) bool {
+	return math.Abs(a-b) <= 1e-12*math.Max(math.Abs(a), math.Abs(b))
 }
 
 func (pc *ProductionChain) FillChain() error {
 	return pc.FillChainExcluding(nil)
 }
 
+// FillChainExcluding fills the chain, treating the given items as supplied from outside it: the chain uses them but
+// doesn't make them
 func (pc *ProductionChain) FillChainExcluding(exclusions []string) error {
-	excluded := make(map[string]struct{})
+	err := pc.proliferator.Validate()
+	if err != nil {
+		return err
+	}
 	for _, ex := range exclusions {
-		excluded[ex] = struct{}{}
+		pc.supplied[ex] = struct{}{}
 	}
 
 	var complete bool
@@ -121,7 +369,7 @@ func (pc *ProductionChain) FillChainExcluding(exclusions []string) error {
 		for i, step := range pc.Steps {
 			if step.Process == nil {
 				complete = false
-				err := pc.fillOneChainExcluding(i, excluded)
+				err := pc.fillOneChainExcluding(i, pc.supplied)
 				if err != nil {
 					return err
 				}
@@ -129,9 +377,98 @@ func (pc *ProductionChain) FillChainExcluding(exclusions []string) error {
 			}
 		}
 	}
+	rates, err := pc.solveRates(pc.requested)
+	if err != nil {
+		return err
+	}
+	for i := range pc.Steps {
+		pc.Steps[i].Rate = rates[i]
+	}
 	return nil
 }
 
+// proliferated returns true if a process has its inputs sprayed.  Raw resources have no inputs to spray, and
+// non-productive processes gain nothing from extra products mode.
+func (pc *ProductionChain) proliferated(proc *Process) bool {
+	if pc.proliferator.Level <= 0 || len(proc.Consumes) == 0 {
+		return false
+	}
+	return pc.proliferator.Mode != ProliferatorExtraProducts || !proc.NonProductive
+}
+
+// Supplied returns how fast the chain uses each of the items supplied from outside it by FillChainExcluding
+func (pc *ProductionChain) Supplied() map[string]float64 {
+	return pc.Consumption(slices.Sorted(maps.Keys(pc.supplied))...)
+}
+
+// Consumption returns how fast the chain's steps consume each of the given items.  This is mostly useful for items
+// that were excluded from the chain, since they have no step of their own.
+func (pc *ProductionChain) Consumption(items ...string) map[string]float64 {
+	rates := make(map[string]float64)
+	for _, step := range pc.Steps {
+		if step.Process == nil {
+			continue
+		}
+		itemsPerRun := pc.itemsPerRun(step.Target, step.Process)
+		if itemsPerRun == 0 {
+			continue
+		}
+		runsPerSecond := step.Rate / itemsPerRun
+		for _, item := range items {
+			if count, ok := step.Process.Consumes[item]; ok {
+				rates[item] += runsPerSecond * float64(count)
+			}
+		}
+	}
+	return rates
+}
+
+// ProliferatorDemand returns the proliferator item used to spray the chain's inputs, and how many of them per
+// second are consumed.  The item is empty if the chain does not use proliferator.
+func (pc *ProductionChain) ProliferatorDemand() (string, float64) {
+	if pc.proliferator.Level <= 0 || pc.proliferator.Level >= len(proliferatorLevels) {
+		return "", 0
+	}
+	var sprayed float64
+	for _, step := range pc.Steps {
+		if step.Process == nil || !pc.proliferated(step.Process) {
+			continue
+		}
+		itemsPerRun := pc.itemsPerRun(step.Target, step.Process)
+		if itemsPerRun == 0 {
+			continue
+		}
+		runsPerSecond := step.Rate / itemsPerRun
+		for _, count := range step.Process.Consumes {
+			sprayed += runsPerSecond * float64(count)
+		}
+	}
+	level := proliferatorLevels[pc.proliferator.Level]
+	return level.item, sprayed / level.sprays
+}
+
+// FactoriesToItemsPerSecond converts a factory count to items per second for a given item, using the process,
+// building and proliferator choices of this chain
+func (pc *ProductionChain) FactoriesToItemsPerSecond(item string, factories float64) (float64, error) {
+	proc, err := pc.selectProcess(item)
+	if err != nil {
+		return 0, err
+	}
+	_, _, perFactory, err := pc.selectBuilding(item, proc)
+	if err != nil {
+		return 0, err
+	}
+	return factories * perFactory, nil
+}
+
+// Factories returns the number of buildings needed to produce this step's rate
+func (ps *ProductionStep) Factories() float64 {
+	if ps.perFactory == 0 {
+		return 0
+	}
+	return ps.Rate / ps.perFactory
+}
+
 func (pc *ProductionChain) GetAllProducible() error {
 	return pc.GetAllProducibleExcluding(nil)
 }
@@ -201,8 +538,12 @@ func (pc *ProductionChain) String() string {
 }
 
 func (pc *ProductionChain) StringWithOpts(opts ...StringOption) string {
+	so := newStringOptions(opts)
 	sb := strings.Builder{}
 	for _, step := range pc.Steps {
+		if !pc.df.InCategory(step.Target, so.categories...) {
+			continue
+		}
 		sb.WriteString(step.StringWithOpts(opts...))
 		sb.WriteString("\n")
 	}
@@ -219,27 +560,64 @@ func WithUnitConverter(conv StringUnitConverterFunc) func(options *StringOptions
 	}
 }
 
-func (ps *ProductionStep) StringWithOpts(opts ...StringOption) string {
-	so := StringOptions{}
-	for _, opt := range opts {
-		opt(&so)
+// WithBuildings adds the building used for each step, and how many of them are needed, to the output
+func WithBuildings() func(options *StringOptions) {
+	return func(options *StringOptions) {
+		options.showBuildings = true
 	}
-	sb := strings.Builder{}
-	rr := ""
-	if ps.Rate > 0 {
-		rate := ps.Rate
-		suffix := "/s"
-		if so.converterFunc != nil {
-			convert, newRate, newSuffix := so.converterFunc(ps.Target, rate)
-			if convert {
-				rate, suffix = newRate, newSuffix
-			}
+}
+
+// WithCategories only lists steps whose target is in one of the given item categories
+func WithCategories(categories ...ItemCategory) func(options *StringOptions) {
+	return func(options *StringOptions) {
+		options.categories = append(options.categories, categories...)
+	}
+}
+
+// WithPrecision shows numbers to the given number of decimal places.  A negative precision shows as many as needed to
+// represent each number exactly.
+func WithPrecision(places int) func(options *StringOptions) {
+	return func(options *StringOptions) {
+		options.precision = places
+	}
+}
+
+// FormatNumber formats a number without scientific notation and removes trailing zeros.  It shows DefaultPrecision
+// decimal places unless WithPrecision is given.
+func FormatNumber(f float64, opts ...StringOption) string {
+	return newStringOptions(opts).number(f)
+}
+
+// number formats a number to the options' precision, without scientific notation or trailing zeros
+func (so *StringOptions) number(f float64) string {
+	s := strconv.FormatFloat(f, 'f', so.precision, 64)
+	if strings.Contains(s, ".") {
+		s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	}
+	if s == "-0" {
+		s = "0"
+	}
+	return s
+}
+
+// rateString formats an item's rate for display, with a leading space, or returns nothing if there is no rate
+func (so *StringOptions) rateString(item string, rate float64) string {
+	if rate <= 0 {
+		return ""
+	}
+	if so.converterFunc != nil {
+		convert, newRate, newSuffix := so.converterFunc(item, rate)
+		if convert {
+			return fmt.Sprintf(" (%s%s)", so.number(newRate), newSuffix)
 		}
-		// Format without scientific notation and remove trailing zeros
-		rr = fmt.Sprintf(" (%s%s)", strings.TrimRight(strings.TrimRight(fmt.Sprintf("%.3f", rate), "0"), "."), suffix)
 	}
+	return fmt.Sprintf(" (%s)", so.rate(rate))
+}
 
-	sb.WriteString(fmt.Sprintf("%s%s: ", ps.Target, rr))
+func (ps *ProductionStep) StringWithOpts(opts ...StringOption) string {
+	so := newStringOptions(opts)
+	sb := strings.Builder{}
+	sb.WriteString(fmt.Sprintf("%s%s: ", ps.Target, so.rateString(ps.Target, ps.Rate)))
 
 	if ps.Process == nil {
 		sb.WriteString("<unknown>")
@@ -250,6 +628,9 @@ func (ps *ProductionStep) StringWithOpts(opts ...StringOption) string {
 		} else {
 			sb.WriteString(fmt.Sprintf("<produced by %s>", strings.Join(ps.Process.Facility, " or ")))
 		}
+		if so.showBuildings && ps.Building != "" && ps.Rate > 0 {
+			sb.WriteString(fmt.Sprintf(" [%s %s]", so.number(ps.Factories()), ps.Building))
+		}
 	}
 	return sb.String()
 }
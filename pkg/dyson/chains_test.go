@@ -1,6 +1,10 @@
 package dyson
 
 import (
+	"fmt"
+	"maps"
+	"math"
+	"slices"
 	"strings"
 	"testing"
 )
@@ -711,7 +715,7 @@ func TestProductionChain_RateCalculation_SingleConsumer(t *testing.T) {
 	}
 
 	// Verify rates are calculated correctly
-	expectedRates := map[string]float32{
+	expectedRates := map[string]float64{
 		"Iron Ingot": 5.0,
 		"Iron Ore":   5.0, // Iron Ingot makes 1, consumes 1 Iron Ore
 	}
@@ -746,7 +750,7 @@ func TestProductionChain_RateCalculation_MultipleConsumers(t *testing.T) {
 	}
 
 	// Verify rates are accumulated correctly from multiple consumers
-	expectedRates := map[string]float32{
+	expectedRates := map[string]float64{
 		"Electric Motor": 3.0,
 		"Gear":           3.0, // Electric Motor needs 1 Gear each, so 3/s
 		"Iron Ingot":     3.0, // Gear needs 1 Iron Ingot each, so 3/s
@@ -781,7 +785,7 @@ func TestProductionChain_RateCalculation_ComplexRecipe(t *testing.T) {
 
 	// Circuit Board makes 2 per run, consumes 2 Iron Ingot + 1 Copper Ingot
 	// For 6/s: need 3 runs/s, so need 6 Iron Ingot/s + 3 Copper Ingot/s
-	expectedRates := map[string]float32{
+	expectedRates := map[string]float64{
 		"Circuit Board": 6.0,
 		"Iron Ingot":    6.0,
 		"Copper Ingot":  3.0,
@@ -1052,3 +1056,391 @@ func TestProductionChain_MermaidGraph_NodeNameEncoding(t *testing.T) {
 		})
 	}
 }
+
+func TestProductionChain_RateCalculation_LateDemand(t *testing.T) {
+	df := getTestDataFile(t)
+
+	// Gear is filled before Electric Motor adds its own demand for Iron Ore, and Iron Ingot is requested
+	// directly as well as through Gear.  Demand discovered after a step is filled must still reach its inputs.
+	pc := df.NewChain([]string{"Gear", "Iron Ingot", "Electric Motor"})
+	for item, rate := range map[string]float64{"Gear": 1, "Iron Ingot": 2, "Electric Motor": 1} {
+		err := pc.SetRate(item, rate)
+		if err != nil {
+			t.Fatalf("SetRate() failed: %v", err)
+		}
+	}
+	err := pc.FillChain()
+	if err != nil {
+		t.Fatalf("FillChain() failed: %v", err)
+	}
+
+	expectedRates := map[string]float64{
+		"Gear":       2, // 1 requested + 1 for Electric Motor
+		"Iron Ingot": 4, // 2 requested + 2 for Gear
+		"Iron Ore":   6, // 4 for Iron Ingot + 2 for Electric Motor
+	}
+	for _, step := range pc.Steps {
+		expectedRate, exists := expectedRates[step.Target]
+		if !exists {
+			continue
+		}
+		if step.Rate != expectedRate {
+			t.Errorf("Step %s: expected rate %.3f, got %.3f", step.Target, expectedRate, step.Rate)
+		}
+	}
+}
+
+func TestProductionChain_RateCalculation_Loop(t *testing.T) {
+	// Each run makes 3 seeds from 2 seeds and an iron ore, so a loop that feeds seeds back makes 1 net seed per run
+	df, err := LoadData([]byte(chainTestYAMLData + `
+  - makes:
+      Seed: 3
+    consumes:
+      Seed: 2
+      Iron Ore: 1
+    time: 1
+    facility: [ assembler ]
+`))
+	if err != nil {
+		t.Fatalf("Failed to load test data: %v", err)
+	}
+	pc := df.NewChain([]string{"Seed"})
+	err = pc.SetRate("Seed", 1)
+	if err != nil {
+		t.Fatalf("SetRate() failed: %v", err)
+	}
+	err = pc.FillChain()
+	if err != nil {
+		t.Fatalf("FillChain() failed: %v", err)
+	}
+
+	expectedRates := map[string]float64{
+		"Seed":     3, // 1 delivered + 2 fed back
+		"Iron Ore": 1,
+	}
+	for _, step := range pc.Steps {
+		want := expectedRates[step.Target]
+		if math.Abs(step.Rate-want) > 1e-9*want {
+			t.Errorf("Step %s: expected rate %v, got %v", step.Target, want, step.Rate)
+		}
+	}
+}
+
+func TestProductionChain_WithExcludedItems(t *testing.T) {
+	df, err := LoadData([]byte(diffTestYAMLData))
+	if err != nil {
+		t.Fatalf("Failed to load test data: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		targets  []string
+		excluded []string
+		steps    []string
+		errMsg   string
+	}{
+		{
+			name:     "another process is chosen",
+			targets:  []string{"Gear"},
+			excluded: []string{"Iron Ingot"},
+			steps:    []string{"Gear", "Iron Ore"},
+		},
+		{
+			name:     "every process uses an excluded item",
+			targets:  []string{"Gear"},
+			excluded: []string{"Iron Ingot", "Iron Ore"},
+			errMsg:   "every process for Gear uses an excluded item",
+		},
+		{
+			name:     "excluded target",
+			targets:  []string{"Gear"},
+			excluded: []string{"Gear"},
+			errMsg:   "Gear is needed but is excluded",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pc := df.NewChain(tt.targets, WithExcludedItems(tt.excluded...))
+			err := pc.FillChain()
+			if tt.errMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+					t.Fatalf("FillChain() error = %v, want error containing %q", err, tt.errMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("FillChain() failed: %v", err)
+			}
+			var steps []string
+			for _, step := range pc.Steps {
+				steps = append(steps, step.Target)
+			}
+			if !slices.Equal(steps, tt.steps) {
+				t.Errorf("steps = %v, want %v", steps, tt.steps)
+			}
+		})
+	}
+}
+
+func TestProductionChain_Supplied(t *testing.T) {
+	df := getTestDataFile(t)
+	pc := df.NewChain([]string{"Circuit Board"})
+	err := pc.SetRate("Circuit Board", 4)
+	if err != nil {
+		t.Fatalf("SetRate() failed: %v", err)
+	}
+	err = pc.FillChainExcluding([]string{"Iron Ingot", "Stone"})
+	if err != nil {
+		t.Fatalf("FillChainExcluding() failed: %v", err)
+	}
+	// Stone is supplied but not used, so it isn't listed
+	want := map[string]float64{"Iron Ingot": 4}
+	if got := pc.Supplied(); !maps.Equal(got, want) {
+		t.Errorf("Supplied() = %v, want %v", got, want)
+	}
+	if _, ok := pc.RawResources()["Iron Ore"]; ok {
+		t.Error("RawResources() includes ore for the supplied ingots")
+	}
+}
+
+func TestProductionChain_WithRecipe(t *testing.T) {
+	df := getTestDataFile(t)
+
+	// Special Item is only available through a special process, which WithRecipe allows
+	pc := df.NewChain([]string{"Special Item"}, WithRecipe("Special Item", []string{"Iron Ingot"}))
+	err := pc.FillChain()
+	if err != nil {
+		t.Fatalf("FillChain() failed: %v", err)
+	}
+	if pc.Steps[0].Process == nil || !pc.Steps[0].Process.Special {
+		t.Error("FillChain() should use the special process chosen with WithRecipe")
+	}
+
+	// A recipe whose inputs don't match any process is an error
+	pc = df.NewChain([]string{"Gear"}, WithRecipe("Gear", []string{"Copper Ingot"}))
+	err = pc.FillChain()
+	if err == nil || !strings.Contains(err.Error(), "consumes exactly") {
+		t.Errorf("FillChain() error = %v, want error containing 'consumes exactly'", err)
+	}
+}
+
+func TestProductionChain_Buildings(t *testing.T) {
+	df, err := LoadData([]byte(testYAMLData))
+	if err != nil {
+		t.Fatalf("Failed to load test data: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		opts      []ChainOption
+		building  string
+		factories float64
+	}{
+		{
+			name:      "default building",
+			building:  "Arc Smelter",
+			factories: 4,
+		},
+		{
+			name:      "chosen building",
+			opts:      []ChainOption{WithBuilding("smelter", "Plane Smelter")},
+			building:  "Plane Smelter",
+			factories: 2,
+		},
+		{
+			name:      "proliferator speedup",
+			opts:      []ChainOption{WithProliferator(Proliferator{Level: 3, Mode: ProliferatorSpeedup})},
+			building:  "Arc Smelter",
+			factories: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pc := df.NewChain([]string{"Iron Ingot"}, tt.opts...)
+			err := pc.SetRate("Iron Ingot", 4)
+			if err != nil {
+				t.Fatalf("SetRate() failed: %v", err)
+			}
+			err = pc.FillChain()
+			if err != nil {
+				t.Fatalf("FillChain() failed: %v", err)
+			}
+			step := pc.Steps[0]
+			if step.Facility != "smelter" {
+				t.Errorf("Facility = %q, want %q", step.Facility, "smelter")
+			}
+			if step.Building != tt.building {
+				t.Errorf("Building = %q, want %q", step.Building, tt.building)
+			}
+			if step.Factories() != tt.factories {
+				t.Errorf("Factories() = %.3f, want %.3f", step.Factories(), tt.factories)
+			}
+		})
+	}
+
+	// Unknown buildings are reported when filling
+	pc := df.NewChain([]string{"Iron Ingot"}, WithBuilding("smelter", "Nonexistent Smelter"))
+	err = pc.FillChain()
+	if err == nil || !strings.Contains(err.Error(), "unknown building") {
+		t.Errorf("FillChain() error = %v, want error containing 'unknown building'", err)
+	}
+}
+
+func TestProductionChain_ProliferatorExtraProducts(t *testing.T) {
+	df := getTestDataFile(t)
+
+	pc := df.NewChain([]string{"Circuit Board"}, WithProliferator(Proliferator{Level: 3, Mode: ProliferatorExtraProducts}))
+	err := pc.SetRate("Circuit Board", 5)
+	if err != nil {
+		t.Fatalf("SetRate() failed: %v", err)
+	}
+	err = pc.FillChain()
+	if err != nil {
+		t.Fatalf("FillChain() failed: %v", err)
+	}
+
+	// Each run makes 2.5 Circuit Boards instead of 2, so 5/s takes 2 runs/s.  Iron Ingot gets 25% extra as well,
+	// but Iron Ore is mined and can't be sprayed.
+	expectedRates := map[string]float64{
+		"Circuit Board": 5,
+		"Iron Ingot":    4,
+		"Copper Ingot":  2,
+		"Iron Ore":      3.2,
+		"Copper Ore":    1.6,
+	}
+	for _, step := range pc.Steps {
+		if step.Rate != expectedRates[step.Target] {
+			t.Errorf("Step %s: expected rate %.3f, got %.3f", step.Target, expectedRates[step.Target], step.Rate)
+		}
+	}
+
+	// 2 runs/s of Circuit Board consume 6 items/s, and 4.8 ingot smelts/s consume 4.8 ores/s
+	item, rate := pc.ProliferatorDemand()
+	if item != "Proliferator Mk. III" {
+		t.Errorf("ProliferatorDemand() item = %q, want %q", item, "Proliferator Mk. III")
+	}
+	if want := 10.8 / 60; rate < want-0.0001 || rate > want+0.0001 {
+		t.Errorf("ProliferatorDemand() rate = %.4f, want %.4f", rate, want)
+	}
+
+	// Invalid settings are rejected
+	pc = df.NewChain([]string{"Circuit Board"}, WithProliferator(Proliferator{Level: 4, Mode: ProliferatorSpeedup}))
+	err = pc.FillChain()
+	if err == nil {
+		t.Error("FillChain() should reject an invalid proliferator level")
+	}
+}
+
+func TestProductionChain_ProliferatorNonProductive(t *testing.T) {
+	// Belts can't make extra products, so in extra products mode their gears aren't sprayed
+	df, err := LoadData([]byte(chainTestYAMLData + `
+  - makes:
+      Belt: 1
+    consumes:
+      Gear: 1
+    time: 1
+    facility: [ assembler ]
+    nonproductive: true
+`))
+	if err != nil {
+		t.Fatalf("Failed to load test data: %v", err)
+	}
+
+	pc := df.NewChain([]string{"Belt"}, WithProliferator(Proliferator{Level: 3, Mode: ProliferatorExtraProducts}))
+	err = pc.SetRate("Belt", 1)
+	if err != nil {
+		t.Fatalf("SetRate() failed: %v", err)
+	}
+	err = pc.FillChain()
+	if err != nil {
+		t.Fatalf("FillChain() failed: %v", err)
+	}
+
+	// The gears themselves are still made with extra products
+	expectedRates := map[string]float64{
+		"Belt":       1,
+		"Gear":       1,
+		"Iron Ingot": 0.8,
+		"Iron Ore":   0.64,
+	}
+	for _, step := range pc.Steps {
+		if step.Rate != expectedRates[step.Target] {
+			t.Errorf("Step %s: expected rate %v, got %v", step.Target, expectedRates[step.Target], step.Rate)
+		}
+	}
+
+	// 0.8 gear runs/s and 0.64 ingot smelts/s are sprayed, but not the belt runs
+	_, rate := pc.ProliferatorDemand()
+	if want := 1.44 / 60; math.Abs(rate-want) > 1e-12 {
+		t.Errorf("ProliferatorDemand() rate = %v, want %v", rate, want)
+	}
+}
+
+func TestFormatNumber(t *testing.T) {
+	tests := []struct {
+		f         float64
+		precision int
+		want      string
+	}{
+		{f: 1.5, precision: 3, want: "1.5"},
+		{f: 2.0 / 3, precision: 3, want: "0.667"},
+		{f: 2.0 / 3, precision: 6, want: "0.666667"},
+		{f: 100, precision: 0, want: "100"},
+		{f: 0.1, precision: -1, want: "0.1"},
+		{f: -0.0001, precision: 3, want: "0"},
+	}
+	for _, tt := range tests {
+		if got := FormatNumber(tt.f, WithPrecision(tt.precision)); got != tt.want {
+			t.Errorf("FormatNumber(%v) at precision %d = %q, want %q", tt.f, tt.precision, got, tt.want)
+		}
+	}
+}
+
+func TestProductionChain_DeepChainPrecision(t *testing.T) {
+	// Ten steps in a row, each taking 1.2s in a 0.75 speed building, so every step needs exactly 0.48 buildings to
+	// make 0.3 items per second.  Neither 0.3 nor 1.2 is exact in binary, so rounding errors show up here.
+	data := "facilities:\n  assembler:\n    Slow Assembler: 0.75\nprocesses:\n"
+	data += "  - makes: { Item 0: 1 }\n    time: 1.2\n    facility: [ assembler ]\n"
+	for i := 1; i < 10; i++ {
+		data += fmt.Sprintf("  - makes: { Item %d: 1 }\n    consumes: { Item %d: 1 }\n    time: 1.2\n"+
+			"    facility: [ assembler ]\n", i, i-1)
+	}
+	df, err := LoadData([]byte(data))
+	if err != nil {
+		t.Fatalf("Failed to load test data: %v", err)
+	}
+	pc := df.NewChain([]string{"Item 9"})
+	err = pc.SetRate("Item 9", 0.3)
+	if err != nil {
+		t.Fatalf("SetRate() failed: %v", err)
+	}
+	err = pc.FillChain()
+	if err != nil {
+		t.Fatalf("FillChain() failed: %v", err)
+	}
+	if len(pc.Steps) != 10 {
+		t.Fatalf("Expected 10 steps, got %d", len(pc.Steps))
+	}
+
+	// Rounding errors must not build up along the chain, so every step is within a few ulps of the exact answer
+	near := func(got, want float64) bool {
+		return math.Abs(got-want) <= 1e-15*want
+	}
+	for _, step := range pc.Steps {
+		if step.Rate != 0.3 {
+			t.Errorf("Step %s: rate = %v, want 0.3", step.Target, step.Rate)
+		}
+		if got := step.Factories(); !near(got, 0.48) {
+			t.Errorf("Step %s: factories = %v, want 0.48", step.Target, got)
+		}
+	}
+	factories, err := pc.FactoriesToItemsPerSecond("Item 0", 0.48)
+	if err != nil {
+		t.Fatalf("FactoriesToItemsPerSecond() failed: %v", err)
+	}
+	if !near(factories, 0.3) {
+		t.Errorf("FactoriesToItemsPerSecond() = %v, want 0.3", factories)
+	}
+}
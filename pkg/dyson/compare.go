@@ -0,0 +1,126 @@
+package dyson
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"text/tabwriter"
+)
+
+// StepComparison is one item's step in each of two compared chains.  A side is zero if that chain doesn't make the
+// item.
+type StepComparison struct {
+	Item       string
+	RateA      float64
+	RateB      float64
+	FactoriesA float64
+	FactoriesB float64
+	BuildingA  string
+	BuildingB  string
+}
+
+// ChainComparison shows how two production chains for the same targets differ
+type ChainComparison struct {
+	Steps  []StepComparison   // every item in either chain, in the order of chain A and then chain B
+	RawA   map[string]float64 // raw resources pulled by chain A
+	RawB   map[string]float64 // raw resources pulled by chain B
+	PowerA float64            // working power of chain A, in kW
+	PowerB float64            // working power of chain B, in kW
+}
+
+// CompareChains compares two filled production chains, for example with different recipes, buildings or proliferator
+// settings, to show what changing from the first to the second would cost or save
+func CompareChains(a, b *ProductionChain) *ChainComparison {
+	c := &ChainComparison{
+		RawA:   a.RawResources(),
+		RawB:   b.RawResources(),
+		PowerA: a.Power(),
+		PowerB: b.Power(),
+	}
+	index := make(map[string]int)
+	add := func(step *ProductionStep) *StepComparison {
+		i, ok := index[step.Target]
+		if !ok {
+			c.Steps = append(c.Steps, StepComparison{Item: step.Target})
+			i = len(c.Steps) - 1
+			index[step.Target] = i
+		}
+		return &c.Steps[i]
+	}
+	for _, step := range a.Steps {
+		sc := add(&step)
+		sc.RateA, sc.FactoriesA, sc.BuildingA = step.Rate, step.Factories(), step.Building
+	}
+	for _, step := range b.Steps {
+		sc := add(&step)
+		sc.RateB, sc.FactoriesB, sc.BuildingB = step.Rate, step.Factories(), step.Building
+	}
+	return c
+}
+
+// String formats the comparison as tables of the A and B values and the change from A to B
+func (c *ChainComparison) String() string {
+	return c.StringWithOpts()
+}
+
+// StringWithOpts formats the comparison with the given options
+func (c *ChainComparison) StringWithOpts(opts ...StringOption) string {
+	so := newStringOptions(opts)
+	sb := strings.Builder{}
+	delta := func(a, b float64) string {
+		d := b - a
+		switch {
+		case so.number(d) == "0":
+			return "="
+		case d > 0:
+			return "+" + so.number(d)
+		default:
+			return so.number(d)
+		}
+	}
+	rateDelta := func(a, b float64) string {
+		return delta(so.unit.FromPerSecond(a), so.unit.FromPerSecond(b))
+	}
+	factories := func(n float64, building string) string {
+		if building == "" || n == 0 {
+			return "-"
+		}
+		return so.number(n) + " " + building
+	}
+
+	tw := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Item\tRate A\tRate B\tChange\tFactories A\tFactories B\tChange")
+	for _, s := range c.Steps {
+		factoryDelta := delta(s.FactoriesA, s.FactoriesB)
+		if s.BuildingA != s.BuildingB && s.BuildingA != "" && s.BuildingB != "" {
+			factoryDelta = "(building changed)"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", s.Item, so.rate(s.RateA), so.rate(s.RateB),
+			rateDelta(s.RateA, s.RateB), factories(s.FactoriesA, s.BuildingA), factories(s.FactoriesB, s.BuildingB),
+			factoryDelta)
+	}
+	_ = tw.Flush()
+
+	raw := slices.Sorted(maps.Keys(c.RawA))
+	for item := range c.RawB {
+		if !slices.Contains(raw, item) {
+			raw = append(raw, item)
+		}
+	}
+	slices.Sort(raw)
+	if len(raw) > 0 {
+		sb.WriteString("\n")
+		tw = tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "Raw resource\tA\tB\tChange")
+		for _, item := range raw {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", item, so.rate(c.RawA[item]), so.rate(c.RawB[item]),
+				rateDelta(c.RawA[item], c.RawB[item]))
+		}
+		_ = tw.Flush()
+	}
+
+	sb.WriteString(fmt.Sprintf("\nPower: %s kW -> %s kW (%s)\n", so.number(c.PowerA), so.number(c.PowerB),
+		delta(c.PowerA, c.PowerB)))
+	return sb.String()
+}
@@ -0,0 +1,105 @@
+package dyson
+
+import (
+	"strings"
+	"testing"
+)
+
+var powerTestYAMLData = testYAMLData + `
+power:
+  Arc Smelter: 360
+  Plane Smelter: 1440
+  Assembling Machine Mk. I: 270
+  Assembling Machine Mk. II: 480
+  Mining Machine: 420
+`
+
+func TestProductionChain_Power(t *testing.T) {
+	df, err := LoadData([]byte(powerTestYAMLData))
+	if err != nil {
+		t.Fatalf("Failed to load test data: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		opts  []ChainOption
+		power float64
+	}{
+		{
+			// 3 mines, 1.5 Arc Smelters and 1.5 Mk. II assemblers
+			name:  "default buildings",
+			power: 3*420 + 1.5*360 + 1.5*480,
+		},
+		{
+			name:  "faster buildings",
+			opts:  []ChainOption{WithBuilding("smelter", "Plane Smelter")},
+			power: 3*420 + 0.75*1440 + 1.5*480,
+		},
+		{
+			// Speeding up the gears and ingots at level 1 takes 1/1.25 of the buildings, each drawing 30% more power
+			name:  "proliferated",
+			opts:  []ChainOption{WithProliferator(Proliferator{Level: 1, Mode: ProliferatorSpeedup})},
+			power: 3*420 + (1.5*360+1.5*480)/1.25*1.3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ch := df.NewChain([]string{"Gear"}, tt.opts...)
+			err := ch.SetRate("Gear", 1.5)
+			if err != nil {
+				t.Fatalf("SetRate() failed: %v", err)
+			}
+			err = ch.FillChain()
+			if err != nil {
+				t.Fatalf("FillChain() failed: %v", err)
+			}
+			if got := ch.Power(); FormatNumber(got) != FormatNumber(tt.power) {
+				t.Errorf("Power() = %v, want %v", got, tt.power)
+			}
+		})
+	}
+}
+
+func TestCompareChains(t *testing.T) {
+	df, err := LoadData([]byte(powerTestYAMLData))
+	if err != nil {
+		t.Fatalf("Failed to load test data: %v", err)
+	}
+	newChain := func(opts ...ChainOption) *ProductionChain {
+		ch := df.NewChain([]string{"Circuit Board"}, opts...)
+		err := ch.SetRate("Circuit Board", 2)
+		if err != nil {
+			t.Fatalf("SetRate() failed: %v", err)
+		}
+		err = ch.FillChainExcluding([]string{"Copper Ingot"})
+		if err != nil {
+			t.Fatalf("FillChainExcluding() failed: %v", err)
+		}
+		return ch
+	}
+	a := newChain(WithBuilding("assembler", "Assembling Machine Mk. I"))
+	b := newChain()
+	c := CompareChains(a, b)
+
+	if len(c.Steps) != 3 {
+		t.Fatalf("CompareChains() has %d steps, want 3", len(c.Steps))
+	}
+	s := c.Steps[0]
+	if s.Item != "Circuit Board" || s.RateA != 2 || s.RateB != 2 || s.FactoriesA != 4.0/3 || s.FactoriesB != 1 ||
+		s.BuildingA != "Assembling Machine Mk. I" || s.BuildingB != "Assembling Machine Mk. II" {
+		t.Errorf("Circuit Board step = %+v", s)
+	}
+	if c.RawA["Iron Ore"] != 2 || c.RawB["Iron Ore"] != 2 {
+		t.Errorf("raw resources = %v and %v, want 2 Iron Ore each", c.RawA, c.RawB)
+	}
+	if FormatNumber(c.PowerB-c.PowerA) != "120" {
+		t.Errorf("power change = %v, want 120", c.PowerB-c.PowerA)
+	}
+	out := c.String()
+	for _, want := range []string{"(building changed)", "Power: 2760 kW -> 2880 kW (+120)"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("String() missing %q:\n%s", want, out)
+		}
+	}
+}
@@ -1,44 +1,156 @@
 package dyson
 
 import (
+	"bytes"
 	"fmt"
 	yaml "gopkg.in/yaml.v3"
+	"maps"
+	"slices"
+	"strings"
 )
 
 type DataFile struct {
-	Facilities    map[string]map[string]float32 `yaml:"facilities"`
+	Facilities    map[string]map[string]float64 `yaml:"facilities"`
+	Power         map[string]float64            `yaml:"power,omitempty"` // working power of each building, in kW
+	Items         map[string]Item               `yaml:"items,omitempty"`
+	Technologies  map[string]Technology         `yaml:"technologies,omitempty"`
 	Processes     []Process                     `yaml:"processes"`
 	procsByTarget map[string][]Process          `yaml:"-"`
+	procsByInput  map[string][]Process          `yaml:"-"`
+	facilityPos   map[string]Position           `yaml:"-"`
+	powerPos      map[string]Position           `yaml:"-"`
 }
 
 type Process struct {
 	Makes    map[string]int `yaml:"makes"`
-	Consumes map[string]int `yaml:"consumes"`
-	Time     float32        `yaml:"time"`
-	Facility []string       `yaml:"facility"`
-	Special  bool           `yaml:"special"`
+	Consumes map[string]int `yaml:"consumes,omitempty"`
+	Time     float64        `yaml:"time"`
+	Facility []string       `yaml:"facility,flow"`
+	Special  bool           `yaml:"special,omitempty"`
+	Tech     string         `yaml:"tech,omitempty"` // unlocking technology, if not that of the items it makes
+	ID       int            `yaml:"id,omitempty"`   // recipe ID in the game, used for blueprints
+
+	NonProductive bool `yaml:"nonproductive,omitempty"` // can't make extra products with proliferator
+
+	pos Position // where the process was defined
+}
+
+// NewDataFile creates a data file from facilities and processes built in code
+func NewDataFile(facilities map[string]map[string]float64, processes []Process) *DataFile {
+	df := &DataFile{
+		Facilities: facilities,
+		Processes:  processes,
+	}
+	df.index()
+	return df
 }
 
 func LoadData(data []byte) (*DataFile, error) {
+	return loadNamedData("", data)
+}
+
+// loadNamedData loads a data file, recording positions within the named file for validation messages
+func loadNamedData(name string, data []byte) (*DataFile, error) {
+	var root yaml.Node
+	err := yaml.Unmarshal(data, &root)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse data: %w", err)
+	}
 	var df DataFile
-	err := yaml.Unmarshal(data, &df)
+	err = root.Decode(&df)
 	if err != nil {
 		return nil, fmt.Errorf("could not parse data: %w", err)
 	}
+	df.recordPositions(name, &root)
+	df.index()
+	return &df, nil
+}
+
+// Marshal writes the data file in the same YAML layout it is loaded from
+func (df *DataFile) Marshal() ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	err := enc.Encode(df)
+	if err != nil {
+		return nil, fmt.Errorf("could not encode data: %w", err)
+	}
+	err = enc.Close()
+	if err != nil {
+		return nil, fmt.Errorf("could not encode data: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// index rebuilds the lookup tables derived from the process list
+func (df *DataFile) index() {
 	df.procsByTarget = make(map[string][]Process)
+	df.procsByInput = make(map[string][]Process)
 	for _, proc := range df.Processes {
 		for m := range proc.Makes {
 			df.procsByTarget[m] = append(df.procsByTarget[m], proc)
 		}
+		for c := range proc.Consumes {
+			df.procsByInput[c] = append(df.procsByInput[c], proc)
+		}
 	}
-	return &df, nil
+}
+
+// HasItem reports whether the data knows an item: it has metadata, or a process makes or consumes it
+func (df *DataFile) HasItem(name string) bool {
+	_, ok := df.Items[name]
+	return ok || len(df.procsByTarget[name]) > 0 || len(df.procsByInput[name]) > 0
+}
+
+// Producers returns every process that makes an item, including special ones
+func (df *DataFile) Producers(item string) []Process {
+	return df.procsByTarget[item]
+}
+
+// ItemsPerSecond returns how fast one building of the given speed makes an item using this process
+func (p *Process) ItemsPerSecond(item string, speed float64) float64 {
+	if p.Time <= 0 {
+		return 0
+	}
+	return float64(p.Makes[item]) * speed / p.Time
+}
+
+// Recipe describes the process with its counts, e.g. "2 Circuit Board from 2 Iron Ingot, 1 Copper Ingot in 1s"
+func (p *Process) Recipe() string {
+	counts := func(items map[string]int) string {
+		var parts []string
+		for _, item := range slices.Sorted(maps.Keys(items)) {
+			parts = append(parts, fmt.Sprintf("%d %s", items[item], item))
+		}
+		return strings.Join(parts, ", ")
+	}
+	desc := counts(p.Makes)
+	if len(p.Consumes) > 0 {
+		desc += " from " + counts(p.Consumes)
+	}
+	desc += fmt.Sprintf(" in %ss", FormatNumber(p.Time))
+	if p.Special {
+		desc += " [special]"
+	}
+	return desc
 }
 
 func (df *DataFile) Makeable(item string) bool {
+	return df.makeable(item, make(map[string]struct{}))
+}
+
+// makeable checks whether an item can be made without depending on any of the items already being visited, so that
+// processes which consume their own outputs don't recurse forever
+func (df *DataFile) makeable(item string, visiting map[string]struct{}) bool {
+	if _, ok := visiting[item]; ok {
+		return false
+	}
+	visiting[item] = struct{}{}
+	defer delete(visiting, item)
 	for _, proc := range df.procsByTarget[item] {
 		result := true
 		for c := range proc.Consumes {
-			if !df.Makeable(c) {
+			if !df.makeable(c, visiting) {
 				result = false
 				break
 			}
@@ -50,94 +162,83 @@ func (df *DataFile) Makeable(item string) bool {
 	return false
 }
 
-func (df *DataFile) Validate() error {
-
-	// Check for duplicate facilities / facility types
-	facTypeMap := make(map[string]struct{})
-	for facType, facs := range df.Facilities {
-		_, ok := facTypeMap[facType]
-		if ok {
-			return fmt.Errorf("duplicate facility type: %s", facType)
+// DefaultBuilding returns the building used for a facility type when none has been chosen: the one with a speed of
+// exactly 1, or else the slowest one.
+func (df *DataFile) DefaultBuilding(facilityType string) (string, float64) {
+	var building string
+	var speed float64
+	for _, name := range slices.Sorted(maps.Keys(df.Facilities[facilityType])) {
+		s := df.Facilities[facilityType][name]
+		if s == 1 {
+			return name, s
 		}
-		facTypeMap[facType] = struct{}{}
-		facMap := make(map[string]struct{})
-		for fac, rate := range facs {
-			_, ok := facMap[fac]
-			if ok {
-				return fmt.Errorf("duplicate facility: %s", fac)
-			}
-			if rate == 0 {
-				return fmt.Errorf("facility rate is zero: %s", fac)
-			}
-			facMap[fac] = struct{}{}
+		if building == "" || s < speed {
+			building, speed = name, s
 		}
 	}
+	return building, speed
+}
 
-	// Make sure every mentioned item is either a resource or makeable
-	items := make(map[string]struct{})
-	for _, process := range df.Processes {
-		for m := range process.Makes {
-			items[m] = struct{}{}
-		}
-		for c := range process.Consumes {
-			items[c] = struct{}{}
+// FacilityType returns the facility type a building belongs to
+func (df *DataFile) FacilityType(building string) (string, bool) {
+	for _, facType := range slices.Sorted(maps.Keys(df.Facilities)) {
+		if _, ok := df.Facilities[facType][building]; ok {
+			return facType, true
 		}
 	}
-	for item := range items {
-		if !df.Makeable(item) {
-			return fmt.Errorf("item cannot be made: %s", item)
+	return "", false
+}
+
+// ProcessByID returns the process with a recipe ID, if the data gives one
+func (df *DataFile) ProcessByID(id int) (*Process, bool) {
+	if id == 0 {
+		return nil, false
+	}
+	for i := range df.Processes {
+		if df.Processes[i].ID == id {
+			return &df.Processes[i], true
 		}
 	}
-	return nil
+	return nil, false
 }
 
 // FactoriesToItemsPerSecond converts a factory count to items per second for a given item
-func (df *DataFile) FactoriesToItemsPerSecond(item string, factories float32) (float32, error) {
-	processes := df.procsByTarget[item]
-	if len(processes) == 0 {
-		return 0, fmt.Errorf("no processes found for item: %s", item)
-	}
-
+func (df *DataFile) FactoriesToItemsPerSecond(item string, factories float64) (float64, error) {
 	// Use the first non-special process
 	var selectedProcess *Process
-	for _, proc := range processes {
+	for _, proc := range df.procsByTarget[item] {
 		if !proc.Special {
 			selectedProcess = &proc
 			break
 		}
 	}
 	if selectedProcess == nil {
-		return 0, fmt.Errorf("no non-special processes found for item: %s", item)
+		return 0, df.noProcessError(item)
 	}
 
 	// Calculate items per second from factories
-	itemsPerRun := float32(selectedProcess.Makes[item])
+	itemsPerRun := float64(selectedProcess.Makes[item])
 	runsPerSecond := 1.0 / selectedProcess.Time
 	itemsPerSecondPerFactory := itemsPerRun * runsPerSecond
 	return factories * itemsPerSecondPerFactory, nil
 }
 
 // ItemsPerSecondToFactories converts items per second to factory count for a given item
-func (df *DataFile) ItemsPerSecondToFactories(item string, itemsPerSecond float32) (float32, error) {
-	processes := df.procsByTarget[item]
-	if len(processes) == 0 {
-		return 0, fmt.Errorf("no processes found for item: %s", item)
-	}
-
+func (df *DataFile) ItemsPerSecondToFactories(item string, itemsPerSecond float64) (float64, error) {
 	// Use the first non-special process
 	var selectedProcess *Process
-	for _, proc := range processes {
+	for _, proc := range df.procsByTarget[item] {
 		if !proc.Special {
 			selectedProcess = &proc
 			break
 		}
 	}
 	if selectedProcess == nil {
-		return 0, fmt.Errorf("no non-special processes found for item: %s", item)
+		return 0, df.noProcessError(item)
 	}
 
 	// Calculate factories from items per second
-	itemsPerRun := float32(selectedProcess.Makes[item])
+	itemsPerRun := float64(selectedProcess.Makes[item])
 	runsPerSecond := 1.0 / selectedProcess.Time
 	itemsPerSecondPerFactory := itemsPerRun * runsPerSecond
 	return itemsPerSecond / itemsPerSecondPerFactory, nil
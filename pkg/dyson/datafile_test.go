@@ -221,7 +221,7 @@ processes: []
 func TestDataFile_ValidateDuplicates(t *testing.T) {
 	// Test duplicate facility type by manually creating DataFile
 	df := &DataFile{
-		Facilities: map[string]map[string]float32{
+		Facilities: map[string]map[string]float64{
 			"smelter": {
 				"Arc Smelter": 1,
 			},
@@ -232,7 +232,7 @@ func TestDataFile_ValidateDuplicates(t *testing.T) {
 
 	// Manually add duplicate facility type (this simulates what would happen
 	// if YAML allowed duplicates)
-	df.Facilities["smelter2"] = map[string]float32{
+	df.Facilities["smelter2"] = map[string]float64{
 		"Plane Smelter": 2,
 	}
 
@@ -277,3 +277,91 @@ processes:
 		t.Error("DataFile.Validate() expected error for unmakeable item, got nil")
 	}
 }
+
+func TestDataFile_MakeableCycle(t *testing.T) {
+	// An item whose only process consumes itself can never be made, and must not recurse forever
+	df, err := LoadData([]byte(`
+facilities:
+  refinery:
+    Oil Refinery: 1
+processes:
+  - makes:
+      Refined Oil: 3
+    consumes:
+      Refined Oil: 2
+    time: 4
+    facility: [ refinery ]
+`))
+	if err != nil {
+		t.Fatalf("Failed to load test data: %v", err)
+	}
+	if df.Makeable("Refined Oil") {
+		t.Error("DataFile.Makeable() should be false for an item that can only be made from itself")
+	}
+}
+
+func TestDataFile_Producers(t *testing.T) {
+	df, err := LoadData([]byte(testYAMLData))
+	if err != nil {
+		t.Fatalf("Failed to load test data: %v", err)
+	}
+	producers := df.Producers("Circuit Board")
+	if len(producers) != 1 {
+		t.Fatalf("Producers(Circuit Board) returned %d processes, want 1", len(producers))
+	}
+	tests := []struct {
+		speed float64
+		want  float64
+	}{
+		{speed: 0.75, want: 1.5},
+		{speed: 1, want: 2},
+		{speed: 2, want: 4},
+	}
+	for _, tt := range tests {
+		if got := producers[0].ItemsPerSecond("Circuit Board", tt.speed); got != tt.want {
+			t.Errorf("ItemsPerSecond(Circuit Board, %v) = %v, want %v", tt.speed, got, tt.want)
+		}
+	}
+	if got := producers[0].ItemsPerSecond("Gear", 1); got != 0 {
+		t.Errorf("ItemsPerSecond() for an item the process doesn't make = %v, want 0", got)
+	}
+	if len(df.Producers("Unobtainium")) != 0 {
+		t.Error("Producers() should be empty for an unknown item")
+	}
+}
+
+func TestDataFile_FacilityType(t *testing.T) {
+	df, err := LoadData([]byte(testYAMLData))
+	if err != nil {
+		t.Fatalf("Failed to load test data: %v", err)
+	}
+	if ft, ok := df.FacilityType("Plane Smelter"); !ok || ft != "smelter" {
+		t.Errorf("FacilityType(Plane Smelter) = %q, %v, want smelter", ft, ok)
+	}
+	if _, ok := df.FacilityType("Mecha"); ok {
+		t.Error("FacilityType() should not find an unknown building")
+	}
+}
+
+func TestDataFile_ProcessByID(t *testing.T) {
+	df, err := LoadData([]byte(testYAMLData + `
+  - makes:
+      Magnet: 1
+    consumes:
+      Iron Ore: 1
+    time: 1.5
+    facility: [ smelter ]
+    id: 2
+`))
+	if err != nil {
+		t.Fatalf("Failed to load test data: %v", err)
+	}
+	if proc, ok := df.ProcessByID(2); !ok || proc.Makes["Magnet"] != 1 {
+		t.Errorf("ProcessByID(2) = %v, %v, want the magnet process", proc, ok)
+	}
+	for _, id := range []int{0, 3} {
+		if _, ok := df.ProcessByID(id); ok {
+			t.Errorf("ProcessByID(%d) should not find a process", id)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package dyson
+
+import (
+	"fmt"
+	"slices"
+)
+
+// DiffItem is an item that one side of a diff can make and the other can't
+type DiffItem struct {
+	Item      string
+	Processes []*Process // processes that side can use to make the item, the one it found first leading; none for inputs
+	Inputs    []string   // items the processes consume that the other side can't make, sorted
+}
+
+// Diff is the difference between what can be made from some items, and what can be made once more are added
+type Diff struct {
+	Added   []DiffItem // items only the new side can make, in the order they were found
+	Removed []DiffItem // items only the old side can make, because the new side excludes something they need
+}
+
+// Diff works out what can be made from the old and new items together, avoiding excludeNew, that can't be made from
+// the old items alone, avoiding excludeOld, and the reverse
+func (df *DataFile) Diff(oldItems, newItems, excludeOld, excludeNew []string) (*Diff, error) {
+	oldChain := df.NewChain(oldItems)
+	err := oldChain.GetAllProducibleExcluding(excludeOld)
+	if err != nil {
+		return nil, fmt.Errorf("error filling old chain: %w", err)
+	}
+	newChain := df.NewChain(append(slices.Clone(oldItems), newItems...))
+	err = newChain.GetAllProducibleExcluding(excludeNew)
+	if err != nil {
+		return nil, fmt.Errorf("error filling new chain: %w", err)
+	}
+	return &Diff{
+		Added:   df.diffItems(newChain, oldChain, excludeNew),
+		Removed: df.diffItems(oldChain, newChain, excludeOld),
+	}, nil
+}
+
+// diffItems lists the items in one chain of producible items that aren't in the other
+func (df *DataFile) diffItems(from, other *ProductionChain, exclusions []string) []DiffItem {
+	can := stepTargets(from)
+	otherCan := stepTargets(other)
+	var result []DiffItem
+	seen := make(map[string]struct{})
+	for _, s := range from.Steps {
+		if _, ok := otherCan[s.Target]; ok {
+			continue
+		}
+		if _, ok := seen[s.Target]; ok {
+			continue
+		}
+		seen[s.Target] = struct{}{}
+		di := DiffItem{Item: s.Target}
+		if s.Process != nil {
+			di.Processes = append(di.Processes, s.Process)
+			found := processKey(s.Process)
+			for i := range df.Processes {
+				proc := &df.Processes[i]
+				if _, ok := proc.Makes[s.Target]; !ok || len(proc.Consumes) == 0 || processKey(proc) == found {
+					continue
+				}
+				if usable(proc, can, exclusions) {
+					di.Processes = append(di.Processes, proc)
+				}
+			}
+		}
+		inputs := make(map[string]struct{})
+		for _, proc := range di.Processes {
+			for item := range proc.Consumes {
+				if _, ok := otherCan[item]; !ok {
+					inputs[item] = struct{}{}
+				}
+			}
+		}
+		for item := range inputs {
+			di.Inputs = append(di.Inputs, item)
+		}
+		slices.Sort(di.Inputs)
+		result = append(result, di)
+	}
+	return result
+}
+
+// usable reports whether a process only consumes items that can be made, and makes nothing that is excluded
+func usable(proc *Process, can map[string]struct{}, exclusions []string) bool {
+	for item := range proc.Makes {
+		if slices.Contains(exclusions, item) {
+			return false
+		}
+	}
+	for item := range proc.Consumes {
+		if _, ok := can[item]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// stepTargets returns the set of items a chain has a step for
+func stepTargets(pc *ProductionChain) map[string]struct{} {
+	targets := make(map[string]struct{})
+	for _, s := range pc.Steps {
+		targets[s.Target] = struct{}{}
+	}
+	return targets
+}
+
+// String describes the item the way a chain step does, e.g. "Glass: Stone"
+func (di *DiffItem) String() string {
+	step := ProductionStep{Target: di.Item}
+	if len(di.Processes) > 0 {
+		step.Process = di.Processes[0]
+	}
+	return step.String()
+}
@@ -0,0 +1,148 @@
+package dyson
+
+import (
+	"maps"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+// diffTestYAMLData adds a second way of making gears, straight from ore
+var diffTestYAMLData = chainTestYAMLData + `
+  - makes:
+      Gear: 1
+    consumes:
+      Iron Ore: 2
+    time: 2
+    facility: [ assembler ]
+`
+
+func TestDataFile_Diff(t *testing.T) {
+	df, err := LoadData([]byte(diffTestYAMLData))
+	if err != nil {
+		t.Fatalf("Failed to load test data: %v", err)
+	}
+
+	// diffItem summarises a DiffItem as its item, the inputs of each of its processes, and its new inputs
+	type diffItem struct {
+		item      string
+		processes [][]string
+		inputs    []string
+	}
+	summarise := func(items []DiffItem) []diffItem {
+		var result []diffItem
+		for _, di := range items {
+			s := diffItem{item: di.Item, inputs: di.Inputs}
+			for _, proc := range di.Processes {
+				s.processes = append(s.processes, slices.Sorted(maps.Keys(proc.Consumes)))
+			}
+			result = append(result, s)
+		}
+		return result
+	}
+
+	tests := []struct {
+		name       string
+		oldItems   []string
+		newItems   []string
+		excludeOld []string
+		excludeNew []string
+		added      []diffItem
+		removed    []diffItem
+	}{
+		{
+			name:     "new resource",
+			oldItems: []string{"Iron Ore"},
+			newItems: []string{"Copper Ore"},
+			added: []diffItem{
+				{item: "Copper Ore"},
+				{item: "Copper Ingot", processes: [][]string{{"Copper Ore"}}, inputs: []string{"Copper Ore"}},
+				{item: "Circuit Board", processes: [][]string{{"Copper Ingot", "Iron Ingot"}},
+					inputs: []string{"Copper Ingot"}},
+			},
+		},
+		{
+			name:     "alternative processes",
+			oldItems: []string{"Copper Ore"},
+			newItems: []string{"Iron Ore"},
+			added: []diffItem{
+				{item: "Iron Ore"},
+				{item: "Iron Ingot", processes: [][]string{{"Iron Ore"}}, inputs: []string{"Iron Ore"}},
+				{item: "Gear", processes: [][]string{{"Iron Ingot"}, {"Iron Ore"}},
+					inputs: []string{"Iron Ingot", "Iron Ore"}},
+				{item: "Circuit Board", processes: [][]string{{"Copper Ingot", "Iron Ingot"}},
+					inputs: []string{"Iron Ingot"}},
+				{item: "Electric Motor", processes: [][]string{{"Gear", "Iron Ore"}},
+					inputs: []string{"Gear", "Iron Ore"}},
+				{item: "Special Item", processes: [][]string{{"Iron Ingot"}}, inputs: []string{"Iron Ingot"}},
+			},
+		},
+		{
+			name:       "excluded process is not listed",
+			oldItems:   []string{"Copper Ore"},
+			newItems:   []string{"Iron Ore"},
+			excludeNew: []string{"Iron Ingot", "Special Item", "Circuit Board"},
+			added: []diffItem{
+				{item: "Iron Ore"},
+				{item: "Gear", processes: [][]string{{"Iron Ore"}}, inputs: []string{"Iron Ore"}},
+				{item: "Electric Motor", processes: [][]string{{"Gear", "Iron Ore"}},
+					inputs: []string{"Gear", "Iron Ore"}},
+			},
+		},
+		{
+			name:       "removed by a new exclusion",
+			oldItems:   []string{"Iron Ore", "Copper Ore"},
+			excludeNew: []string{"Copper Ingot"},
+			removed: []diffItem{
+				{item: "Copper Ingot", processes: [][]string{{"Copper Ore"}}},
+				{item: "Circuit Board", processes: [][]string{{"Copper Ingot", "Iron Ingot"}},
+					inputs: []string{"Copper Ingot"}},
+			},
+		},
+		{
+			name:       "exclusion lifted",
+			oldItems:   []string{"Iron Ore"},
+			excludeOld: []string{"Gear"},
+			added: []diffItem{
+				{item: "Gear", processes: [][]string{{"Iron Ingot"}, {"Iron Ore"}}},
+				{item: "Electric Motor", processes: [][]string{{"Gear", "Iron Ore"}}, inputs: []string{"Gear"}},
+			},
+		},
+		{
+			name:     "nothing new",
+			oldItems: []string{"Iron Ore"},
+			newItems: []string{"Iron Ingot"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := df.Diff(tt.oldItems, tt.newItems, tt.excludeOld, tt.excludeNew)
+			if err != nil {
+				t.Fatalf("Diff() failed: %v", err)
+			}
+			if got := summarise(d.Added); !reflect.DeepEqual(got, tt.added) {
+				t.Errorf("Added = %v, want %v", got, tt.added)
+			}
+			if got := summarise(d.Removed); !reflect.DeepEqual(got, tt.removed) {
+				t.Errorf("Removed = %v, want %v", got, tt.removed)
+			}
+		})
+	}
+}
+
+func TestDiffItem_String(t *testing.T) {
+	df := getTestDataFile(t)
+	d, err := df.Diff([]string{"Iron Ore"}, []string{"Copper Ore"}, nil, nil)
+	if err != nil {
+		t.Fatalf("Diff() failed: %v", err)
+	}
+	var got []string
+	for _, di := range d.Added {
+		got = append(got, di.String())
+	}
+	want := []string{"Copper Ore: <unknown>", "Copper Ingot: Copper Ore", "Circuit Board: Copper Ingot, Iron Ingot"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,63 @@
+package dyson
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// errChainFilled is returned when filling a chain step that has already been filled
+var errChainFilled = errors.New("chain already filled")
+
+// ErrUnknownItem is returned for an item the data doesn't mention at all, which is usually a misspelling
+type ErrUnknownItem struct {
+	Item string
+}
+
+func (e *ErrUnknownItem) Error() string {
+	return fmt.Sprintf("no processes found for %s: unknown item", e.Item)
+}
+
+// ErrNoRecipe is returned when no process makes an item, or none matches the recipe chosen for it
+type ErrNoRecipe struct {
+	Item   string
+	Inputs []string // inputs of the recipe chosen with WithRecipe, if any
+}
+
+func (e *ErrNoRecipe) Error() string {
+	if len(e.Inputs) > 0 {
+		return fmt.Sprintf("no process for %s consumes exactly: %s", e.Item, strings.Join(e.Inputs, ", "))
+	}
+	return fmt.Sprintf("no processes found for %s", e.Item)
+}
+
+// ErrOnlySpecialRecipes is returned when the only processes that make an item are special ones, which are never
+// chosen unless asked for
+type ErrOnlySpecialRecipes struct {
+	Item string
+}
+
+func (e *ErrOnlySpecialRecipes) Error() string {
+	return fmt.Sprintf("no non-special processes found for %s", e.Item)
+}
+
+// ErrCycle is returned when a loop of processes that needs an item to make itself uses at least as much of it as
+// the loop makes, so no rates can keep it going
+type ErrCycle struct {
+	Item string
+}
+
+func (e *ErrCycle) Error() string {
+	return fmt.Sprintf("production cycle involving %s uses at least as much as it makes", e.Item)
+}
+
+// noProcessError returns the error for an item that has no non-special process
+func (df *DataFile) noProcessError(item string) error {
+	if !df.HasItem(item) {
+		return &ErrUnknownItem{Item: item}
+	}
+	if len(df.procsByTarget[item]) > 0 {
+		return &ErrOnlySpecialRecipes{Item: item}
+	}
+	return &ErrNoRecipe{Item: item}
+}
@@ -0,0 +1,172 @@
+package dyson
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// errorTestYAMLData adds an input nothing makes, and two items that are each made from the other
+var errorTestYAMLData = chainTestYAMLData + `
+  - makes:
+      Hammer: 1
+    consumes:
+      Mystery Alloy: 1
+    time: 1
+    facility: [ assembler ]
+
+  - makes:
+      Egg: 1
+    consumes:
+      Chicken: 1
+    time: 1
+    facility: [ assembler ]
+
+  - makes:
+      Chicken: 1
+    consumes:
+      Egg: 1
+    time: 1
+    facility: [ assembler ]
+`
+
+func TestChainErrors(t *testing.T) {
+	df, err := LoadData([]byte(errorTestYAMLData))
+	if err != nil {
+		t.Fatalf("Failed to load test data: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		run  func() error
+		want error // an error of the wanted type, with the wanted fields
+	}{
+		{
+			name: "unknown item",
+			run: func() error {
+				return df.NewChain([]string{"Widget"}).FillChain()
+			},
+			want: &ErrUnknownItem{Item: "Widget"},
+		},
+		{
+			name: "rate for unknown item",
+			run: func() error {
+				return df.NewChain([]string{"Gear"}).SetRate("Widget", 1)
+			},
+			want: &ErrUnknownItem{Item: "Widget"},
+		},
+		{
+			name: "no recipe",
+			run: func() error {
+				return df.NewChain([]string{"Hammer"}).FillChain()
+			},
+			want: &ErrNoRecipe{Item: "Mystery Alloy"},
+		},
+		{
+			name: "no matching recipe",
+			run: func() error {
+				return df.NewChain([]string{"Gear"}, WithRecipe("Gear", []string{"Copper Ingot"})).FillChain()
+			},
+			want: &ErrNoRecipe{Item: "Gear", Inputs: []string{"Copper Ingot"}},
+		},
+		{
+			name: "only special recipes",
+			run: func() error {
+				return df.NewChain([]string{"Special Item"}).FillChain()
+			},
+			want: &ErrOnlySpecialRecipes{Item: "Special Item"},
+		},
+		{
+			name: "cycle",
+			run: func() error {
+				pc := df.NewChain([]string{"Egg"})
+				err := pc.SetRate("Egg", 1)
+				if err != nil {
+					return err
+				}
+				return pc.FillChain()
+			},
+			// Every egg needs a chicken and every chicken an egg, so the rates never settle
+			want: &ErrCycle{Item: "Chicken"},
+		},
+		{
+			name: "factories of unknown item",
+			run: func() error {
+				_, err := df.FactoriesToItemsPerSecond("Widget", 1)
+				return err
+			},
+			want: &ErrUnknownItem{Item: "Widget"},
+		},
+		{
+			name: "factories of item with no recipe",
+			run: func() error {
+				_, err := df.ItemsPerSecondToFactories("Mystery Alloy", 1)
+				return err
+			},
+			want: &ErrNoRecipe{Item: "Mystery Alloy"},
+		},
+		{
+			name: "factories of item with only special recipes",
+			run: func() error {
+				_, err := df.FactoriesToItemsPerSecond("Special Item", 1)
+				return err
+			},
+			want: &ErrOnlySpecialRecipes{Item: "Special Item"},
+		},
+		{
+			name: "through the planner",
+			run: func() error {
+				_, err := df.NewPlanner(WithTargets("Widget:1")).Chain()
+				return err
+			},
+			want: &ErrUnknownItem{Item: "Widget"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.run()
+			if err == nil {
+				t.Fatalf("got no error, want %v", tt.want)
+			}
+			// errors.As needs a pointer to a variable of the wanted type
+			target := reflect.New(reflect.TypeOf(tt.want))
+			if !errors.As(err, target.Interface()) {
+				t.Fatalf("error %v (%T) is not a %T", err, err, tt.want)
+			}
+			if got := target.Elem().Interface(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("error = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChainErrors_AlreadyFilled(t *testing.T) {
+	df := getTestDataFile(t)
+	pc := df.NewChain([]string{"Iron Ingot"})
+	err := pc.fillOneChain(0)
+	if err != nil {
+		t.Fatalf("fillOneChain() failed: %v", err)
+	}
+	err = pc.fillOneChain(0)
+	if !errors.Is(err, errChainFilled) {
+		t.Errorf("fillOneChain() error = %v, want errChainFilled", err)
+	}
+}
+
+func TestDataFile_HasItem(t *testing.T) {
+	df, err := LoadData([]byte(errorTestYAMLData))
+	if err != nil {
+		t.Fatalf("Failed to load test data: %v", err)
+	}
+	tests := map[string]bool{
+		"Gear":          true,  // made
+		"Mystery Alloy": true,  // only consumed
+		"Widget":        false, // not mentioned
+	}
+	for item, want := range tests {
+		if got := df.HasItem(item); got != want {
+			t.Errorf("HasItem(%q) = %v, want %v", item, got, want)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package dyson
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// ItemCategory is the broad kind of an item, used to filter output
+type ItemCategory string
+
+const (
+	CategoryResource  ItemCategory = "resource"
+	CategoryComponent ItemCategory = "component"
+	CategoryBuilding  ItemCategory = "building"
+	CategoryMatrix    ItemCategory = "matrix"
+	CategoryFuel      ItemCategory = "fuel"
+	CategoryCombat    ItemCategory = "combat"
+)
+
+// ItemCategories lists all known item categories
+var ItemCategories = []ItemCategory{
+	CategoryResource,
+	CategoryComponent,
+	CategoryBuilding,
+	CategoryMatrix,
+	CategoryFuel,
+	CategoryCombat,
+}
+
+// ParseItemCategory converts a category name to an ItemCategory
+func ParseItemCategory(s string) (ItemCategory, error) {
+	cat := ItemCategory(strings.ToLower(s))
+	if !slices.Contains(ItemCategories, cat) {
+		return "", fmt.Errorf("unknown item category: %s", s)
+	}
+	return cat, nil
+}
+
+// Item is the metadata for one item.  Items are still identified by name in processes; metadata is optional.
+type Item struct {
+	Category ItemCategory `yaml:"category"`
+	Stack    int          `yaml:"stack,omitempty"` // items per inventory slot
+	Fuel     float64      `yaml:"fuel,omitempty"`  // energy released when burned, in MJ
+	Tech     string       `yaml:"tech,omitempty"`  // technology that unlocks the item
+
+	pos Position // where the item was defined
+}
+
+// Item returns the metadata for an item, if the data file has any
+func (df *DataFile) Item(name string) (Item, bool) {
+	item, ok := df.Items[name]
+	return item, ok
+}
+
+// InCategory reports whether an item belongs to any of the given categories.  Every item matches when no categories
+// are given, and items without metadata only match then.
+func (df *DataFile) InCategory(name string, categories ...ItemCategory) bool {
+	if len(categories) == 0 {
+		return true
+	}
+	item, ok := df.Items[name]
+	return ok && slices.Contains(categories, item.Category)
+}
+
+// ItemsInCategory returns the names of all items in any of the given categories, sorted
+func (df *DataFile) ItemsInCategory(categories ...ItemCategory) []string {
+	var names []string
+	for _, name := range slices.Sorted(maps.Keys(df.Items)) {
+		if df.InCategory(name, categories...) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// describeItem summarises an item's metadata, for conflict messages
+func describeItem(item Item) string {
+	desc := fmt.Sprintf("category %s, stack %d", item.Category, item.Stack)
+	if item.Fuel != 0 {
+		desc += fmt.Sprintf(", fuel %g", item.Fuel)
+	}
+	if item.Tech != "" {
+		desc += ", tech " + item.Tech
+	}
+	return desc
+}
@@ -0,0 +1,115 @@
+package dyson
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+var itemsTestYAMLData = `
+facilities:
+  mine:
+    Mining Machine: 1
+  smelter:
+    Arc Smelter: 1
+  assembler:
+    Assembling Machine Mk. I: 1
+items:
+  Iron Ore: { category: resource, stack: 100 }
+  Coal: { category: resource, stack: 100, fuel: 2.7 }
+  Iron Ingot: { category: component, stack: 100 }
+  Arc Smelter: { category: building, stack: 50, tech: Smelting Purification }
+technologies:
+  Smelting Purification: { cost: { Iron Ingot: 10 } }
+processes:
+  - makes:
+      Iron Ore: 1
+    time: 2
+    facility: [ mine ]
+  - makes:
+      Coal: 1
+    time: 2
+    facility: [ mine ]
+  - makes:
+      Iron Ingot: 1
+    consumes:
+      Iron Ore: 1
+    time: 1
+    facility: [ smelter ]
+  - makes:
+      Arc Smelter: 1
+    consumes:
+      Iron Ingot: 4
+    time: 3
+    facility: [ assembler ]
+  - makes:
+      Gear: 1
+    consumes:
+      Iron Ingot: 1
+    time: 1
+    facility: [ assembler ]
+`
+
+func TestDataFile_Items(t *testing.T) {
+	df, err := LoadData([]byte(itemsTestYAMLData))
+	if err != nil {
+		t.Fatalf("Failed to load test data: %v", err)
+	}
+	err = df.Validate()
+	if err != nil {
+		t.Fatalf("Validate() failed: %v", err)
+	}
+
+	item, ok := df.Item("Arc Smelter")
+	if !ok || item.Category != CategoryBuilding || item.Stack != 50 || item.Tech != "Smelting Purification" {
+		t.Errorf("Item(Arc Smelter) = %+v, %v", item, ok)
+	}
+	if item, _ := df.Item("Coal"); item.Fuel != 2.7 {
+		t.Errorf("Coal fuel = %v, want 2.7", item.Fuel)
+	}
+	if _, ok := df.Item("Gear"); ok {
+		t.Error("Gear should have no metadata")
+	}
+
+	if !df.InCategory("Gear") {
+		t.Error("every item should match when no categories are given")
+	}
+	if df.InCategory("Gear", CategoryComponent) {
+		t.Error("items without metadata should not match a category")
+	}
+	got := df.ItemsInCategory(CategoryResource, CategoryBuilding)
+	want := []string{"Arc Smelter", "Coal", "Iron Ore"}
+	if !slices.Equal(got, want) {
+		t.Errorf("ItemsInCategory() = %v, want %v", got, want)
+	}
+}
+
+func TestProductionChain_WithCategories(t *testing.T) {
+	df, err := LoadData([]byte(itemsTestYAMLData))
+	if err != nil {
+		t.Fatalf("Failed to load test data: %v", err)
+	}
+	ch := df.NewChain([]string{"Iron Ore"})
+	err = ch.GetAllProducible()
+	if err != nil {
+		t.Fatalf("GetAllProducible() failed: %v", err)
+	}
+	got := ch.StringWithOpts(WithCategories(CategoryBuilding))
+	if got != "Arc Smelter: Iron Ingot\n" {
+		t.Errorf("StringWithOpts(WithCategories(building)) = %q", got)
+	}
+	if !strings.Contains(ch.String(), "Gear: Iron Ingot") {
+		t.Errorf("String() should list every step, got %q", ch.String())
+	}
+}
+
+func TestParseItemCategory(t *testing.T) {
+	cat, err := ParseItemCategory("Building")
+	if err != nil || cat != CategoryBuilding {
+		t.Errorf("ParseItemCategory(Building) = %v, %v", cat, err)
+	}
+	_, err = ParseItemCategory("widget")
+	if err == nil || !strings.Contains(err.Error(), "unknown item category: widget") {
+		t.Errorf("ParseItemCategory(widget) error = %v", err)
+	}
+}
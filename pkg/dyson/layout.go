@@ -0,0 +1,258 @@
+package dyson
+
+import (
+	"cmp"
+	"math"
+	"slices"
+)
+
+// layoutNode is a box in a graph layout, or a dummy point where an edge crosses a layer
+type layoutNode struct {
+	item   string
+	dummy  bool
+	layer  int
+	order  float64 // position within the layer while ordering, and then the index
+	x, y   float64 // top left corner
+	height float64
+}
+
+// layoutEdge is an edge of a graph layout, through the dummy nodes it crosses
+type layoutEdge struct {
+	from, to string
+	flow     float64
+	path     []*layoutNode // from node, dummy nodes and to node
+}
+
+// graphLayout places a layered graph, Sugiyama style: nodes go in layers by their longest path from the sources,
+// edges that span several layers get a dummy node in each layer between, layers are reordered to reduce edge
+// crossings, and then nodes are moved towards their neighbours
+type graphLayout struct {
+	nodes  map[string]*layoutNode
+	layers [][]*layoutNode
+	edges  []*layoutEdge
+	width  float64
+	height float64
+}
+
+// layoutSweeps is how many times the crossing reduction sweeps through the layers in each direction
+const layoutSweeps = 8
+
+// newGraphLayout lays out a graph, given its nodes in their preferred order, the height of each node's box and the
+// edges between them with their flows
+func newGraphLayout(items []string, heights map[string]float64, edges []*layoutEdge, nodeWidth, gapX, gapY,
+	margin float64) *graphLayout {
+	var pairs [][2]string
+	for _, e := range edges {
+		pairs = append(pairs, [2]string{e.from, e.to})
+	}
+	columns := graphColumns(items, pairs)
+	gl := &graphLayout{nodes: make(map[string]*layoutNode), edges: edges}
+	addNode := func(n *layoutNode) {
+		for len(gl.layers) <= n.layer {
+			gl.layers = append(gl.layers, nil)
+		}
+		n.order = float64(len(gl.layers[n.layer]))
+		gl.layers[n.layer] = append(gl.layers[n.layer], n)
+	}
+	for _, item := range items {
+		n := &layoutNode{item: item, layer: columns[item], height: heights[item]}
+		gl.nodes[item] = n
+		addNode(n)
+	}
+	for _, e := range edges {
+		from, to := gl.nodes[e.from], gl.nodes[e.to]
+		e.path = []*layoutNode{from}
+		for l := from.layer + 1; l < to.layer; l++ {
+			d := &layoutNode{item: e.from, dummy: true, layer: l}
+			addNode(d)
+			e.path = append(e.path, d)
+		}
+		e.path = append(e.path, to)
+	}
+
+	gl.reduceCrossings()
+	gl.place(nodeWidth, gapX, gapY, margin)
+	return gl
+}
+
+// neighbours returns the nodes joined to each node in the layer before it, or in the layer after it
+func (gl *graphLayout) neighbours(after bool) map[*layoutNode][]*layoutNode {
+	nb := make(map[*layoutNode][]*layoutNode)
+	for _, e := range gl.edges {
+		for i := 0; i+1 < len(e.path); i++ {
+			a, b := e.path[i], e.path[i+1]
+			if a.layer >= b.layer {
+				continue
+			}
+			if after {
+				nb[a] = append(nb[a], b)
+			} else {
+				nb[b] = append(nb[b], a)
+			}
+		}
+	}
+	return nb
+}
+
+// crossings counts the edge segments that cross between adjacent layers
+func (gl *graphLayout) crossings() int {
+	type segment struct{ a, b float64 }
+	byLayer := make(map[int][]segment)
+	for _, e := range gl.edges {
+		for i := 0; i+1 < len(e.path); i++ {
+			a, b := e.path[i], e.path[i+1]
+			if a.layer < b.layer {
+				byLayer[a.layer] = append(byLayer[a.layer], segment{a.order, b.order})
+			}
+		}
+	}
+	count := 0
+	for _, segs := range byLayer {
+		for i := range segs {
+			for j := i + 1; j < len(segs); j++ {
+				if (segs[i].a-segs[j].a)*(segs[i].b-segs[j].b) < 0 {
+					count++
+				}
+			}
+		}
+	}
+	return count
+}
+
+// reduceCrossings reorders the layers by the barycenter heuristic, sweeping back and forth and keeping the order with
+// the fewest crossings
+func (gl *graphLayout) reduceCrossings() {
+	before, after := gl.neighbours(false), gl.neighbours(true)
+	save := func() [][]*layoutNode {
+		saved := make([][]*layoutNode, len(gl.layers))
+		for i, layer := range gl.layers {
+			saved[i] = slices.Clone(layer)
+		}
+		return saved
+	}
+	best, bestCrossings := save(), gl.crossings()
+	sortLayer := func(layer []*layoutNode, nb map[*layoutNode][]*layoutNode) {
+		bary := make(map[*layoutNode]float64)
+		for _, n := range layer {
+			bary[n] = n.order
+			if len(nb[n]) > 0 {
+				sum := 0.0
+				for _, m := range nb[n] {
+					sum += m.order
+				}
+				bary[n] = sum / float64(len(nb[n]))
+			}
+		}
+		slices.SortStableFunc(layer, func(a, b *layoutNode) int {
+			return cmp.Compare(bary[a], bary[b])
+		})
+		for i, n := range layer {
+			n.order = float64(i)
+		}
+	}
+	for sweep := 0; sweep < layoutSweeps && bestCrossings > 0; sweep++ {
+		for l := 1; l < len(gl.layers); l++ {
+			sortLayer(gl.layers[l], before)
+		}
+		for l := len(gl.layers) - 2; l >= 0; l-- {
+			sortLayer(gl.layers[l], after)
+		}
+		gl.transpose()
+		if c := gl.crossings(); c < bestCrossings {
+			best, bestCrossings = save(), c
+		}
+	}
+	gl.layers = best
+	for _, layer := range gl.layers {
+		for i, n := range layer {
+			n.order = float64(i)
+		}
+	}
+}
+
+// transpose swaps neighbouring nodes within each layer while that reduces the number of crossings
+func (gl *graphLayout) transpose() {
+	crossings := gl.crossings()
+	for improved := true; improved; {
+		improved = false
+		for _, layer := range gl.layers {
+			for i := 0; i+1 < len(layer); i++ {
+				layer[i], layer[i+1] = layer[i+1], layer[i]
+				layer[i].order, layer[i+1].order = float64(i), float64(i+1)
+				if c := gl.crossings(); c < crossings {
+					crossings, improved = c, true
+					continue
+				}
+				layer[i], layer[i+1] = layer[i+1], layer[i]
+				layer[i].order, layer[i+1].order = float64(i), float64(i+1)
+			}
+		}
+	}
+}
+
+// place works out the coordinates of the nodes.  Each layer is a column, and within it nodes are moved towards the
+// average height of their neighbours, as far as the order and spacing of the layer allows.
+func (gl *graphLayout) place(nodeWidth, gapX, gapY, margin float64) {
+	before, after := gl.neighbours(false), gl.neighbours(true)
+	center := func(n *layoutNode) float64 { return n.y + n.height/2 }
+	// stack places a layer's nodes in order, each as near as it can be to where it wants to be, and then moves the whole
+	// layer so that on average the nodes are neither too high nor too low
+	stack := func(layer []*layoutNode, want func(n *layoutNode) float64) {
+		wanted := make([]float64, len(layer))
+		y := math.Inf(-1)
+		for i, n := range layer {
+			wanted[i] = want(n) - n.height/2
+			n.y = max(wanted[i], y)
+			y = n.y + n.height + gapY
+		}
+		shift := 0.0
+		for i, n := range layer {
+			shift += wanted[i] - n.y
+		}
+		for _, n := range layer {
+			n.y += shift / float64(len(layer))
+		}
+	}
+	for _, layer := range gl.layers {
+		stack(layer, func(n *layoutNode) float64 { return 0 })
+	}
+	average := func(nb map[*layoutNode][]*layoutNode) func(n *layoutNode) float64 {
+		return func(n *layoutNode) float64 {
+			if len(nb[n]) == 0 {
+				return center(n)
+			}
+			sum := 0.0
+			for _, m := range nb[n] {
+				sum += center(m)
+			}
+			return sum / float64(len(nb[n]))
+		}
+	}
+	for pass := 0; pass < 4; pass++ {
+		for l := 1; l < len(gl.layers); l++ {
+			stack(gl.layers[l], average(before))
+		}
+		for l := len(gl.layers) - 2; l >= 0; l-- {
+			stack(gl.layers[l], average(after))
+		}
+	}
+
+	top, bottom := math.Inf(1), math.Inf(-1)
+	for _, layer := range gl.layers {
+		for _, n := range layer {
+			top = min(top, n.y)
+			bottom = max(bottom, n.y+n.height)
+		}
+	}
+	for l, layer := range gl.layers {
+		for _, n := range layer {
+			n.x = margin + float64(l)*(nodeWidth+gapX)
+			n.y = math.Round(n.y + margin - top)
+		}
+	}
+	gl.width = 2*margin + float64(len(gl.layers))*(nodeWidth+gapX) - gapX
+	gl.height = math.Ceil(2*margin + bottom - top)
+	if len(gl.layers) == 0 {
+		gl.width, gl.height = 2*margin, 2*margin
+	}
+}
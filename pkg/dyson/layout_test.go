@@ -0,0 +1,72 @@
+package dyson
+
+import (
+	"testing"
+)
+
+func newTestLayout(items []string, pairs [][2]string) *graphLayout {
+	heights := make(map[string]float64)
+	for _, item := range items {
+		heights[item] = 40
+	}
+	var edges []*layoutEdge
+	for _, p := range pairs {
+		edges = append(edges, &layoutEdge{from: p[0], to: p[1], flow: 1})
+	}
+	return newGraphLayout(items, heights, edges, 100, 50, 10, 5)
+}
+
+func TestGraphLayout_Crossings(t *testing.T) {
+	// In the given order, A-Y and B-X cross, and then C-X and D-Y cross again
+	gl := newTestLayout([]string{"A", "B", "X", "Y", "C", "D"},
+		[][2]string{{"A", "Y"}, {"B", "X"}, {"X", "C"}, {"Y", "D"}, {"A", "D"}})
+	if c := gl.crossings(); c != 0 {
+		t.Errorf("crossings() = %d after layout, want 0", c)
+	}
+}
+
+func TestGraphLayout_LongEdges(t *testing.T) {
+	gl := newTestLayout([]string{"A", "B", "C"}, [][2]string{{"A", "B"}, {"B", "C"}, {"A", "C"}})
+	if len(gl.layers) != 3 {
+		t.Fatalf("layout has %d layers, want 3", len(gl.layers))
+	}
+	for _, e := range gl.edges {
+		wantPath := 2
+		if e.from == "A" && e.to == "C" {
+			wantPath = 3
+		}
+		if len(e.path) != wantPath {
+			t.Errorf("edge %s-%s has a path of %d nodes, want %d", e.from, e.to, len(e.path), wantPath)
+		}
+		for i := 0; i+1 < len(e.path); i++ {
+			if e.path[i+1].layer != e.path[i].layer+1 {
+				t.Errorf("edge %s-%s skips a layer", e.from, e.to)
+			}
+		}
+	}
+	if len(gl.layers[1]) != 2 || !(gl.layers[1][0].dummy || gl.layers[1][1].dummy) {
+		t.Errorf("middle layer should hold B and a dummy node, got %d nodes", len(gl.layers[1]))
+	}
+}
+
+func TestGraphLayout_Placement(t *testing.T) {
+	gl := newTestLayout([]string{"A", "B", "C", "D"}, [][2]string{{"A", "D"}, {"B", "D"}, {"C", "D"}})
+	for l, layer := range gl.layers {
+		for i, n := range layer {
+			if n.x != 5+float64(l)*150 {
+				t.Errorf("%s: x = %v, want %v", n.item, n.x, 5+float64(l)*150)
+			}
+			if n.y < 5 || n.y+n.height > gl.height-5 {
+				t.Errorf("%s: y = %v is outside the layout", n.item, n.y)
+			}
+			if i > 0 && n.y < layer[i-1].y+layer[i-1].height+10 {
+				t.Errorf("%s overlaps %s", n.item, layer[i-1].item)
+			}
+		}
+	}
+	// D is centred on the middle of its three inputs
+	b, d := gl.nodes["B"], gl.nodes["D"]
+	if b.y+b.height/2 != d.y+d.height/2 {
+		t.Errorf("D is at %v, want it level with B at %v", d.y, b.y)
+	}
+}
@@ -0,0 +1,223 @@
+package dyson
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// MallTarget is an item to keep stocked in a mall, and the rate to refill it at in items per second
+type MallTarget struct {
+	Item string
+	Rate float64
+}
+
+// MallGroup is a set of mall targets that take the same items from the bus, so they can be built side by side
+type MallGroup struct {
+	Inputs  []string         // bus items consumed by the group
+	Targets []string         // mall targets in the group, in construction order
+	Steps   []ProductionStep // steps of the combined chain that the group builds
+}
+
+// Mall is a combined production chain for a set of mall targets, fed from the bus
+type Mall struct {
+	Chain     *ProductionChain
+	Order     []string // suggested construction order of the targets
+	Groups    []MallGroup
+	BusDemand map[string]float64 // rate each bus item is drawn at
+}
+
+// PlanMall works out how to make a set of mall targets from the items on the bus.  Every target needs a rate.  Targets
+// are grouped by the bus items they consume.  The suggested construction order builds targets before anything that uses
+// them, and otherwise keeps groups together, starting with the ones that need the fewest bus items.
+func (df *DataFile) PlanMall(targets []MallTarget, bus []string, opts ...ChainOption) (*Mall, error) {
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no mall targets given")
+	}
+	var names []string
+	for _, t := range targets {
+		if slices.Contains(names, t.Item) {
+			return nil, fmt.Errorf("duplicate mall target: %s", t.Item)
+		}
+		if slices.Contains(bus, t.Item) {
+			return nil, fmt.Errorf("mall target %s is already on the bus", t.Item)
+		}
+		if t.Rate <= 0 {
+			return nil, fmt.Errorf("mall target %s has no rate", t.Item)
+		}
+		names = append(names, t.Item)
+	}
+
+	newChain := func(targets []MallTarget, exclusions []string) (*ProductionChain, error) {
+		var reqs []string
+		for _, t := range targets {
+			reqs = append(reqs, t.Item)
+		}
+		ch := df.NewChain(reqs, opts...)
+		for _, t := range targets {
+			err := ch.SetRate(t.Item, t.Rate)
+			if err != nil {
+				return nil, err
+			}
+		}
+		err := ch.FillChainExcluding(exclusions)
+		if err != nil {
+			return nil, fmt.Errorf("error filling chain: %w", err)
+		}
+		return ch, nil
+	}
+
+	mall := &Mall{}
+	var err error
+	mall.Chain, err = newChain(targets, bus)
+	if err != nil {
+		return nil, err
+	}
+	mall.BusDemand = mall.Chain.Consumption(bus...)
+
+	// Find what each target needs on its own, taking other targets from the mall: the bus items it consumes, the
+	// other targets it uses, and the steps it builds
+	inputs := make(map[string][]string)
+	uses := make(map[string]map[string]struct{})
+	needs := make(map[string]map[string]struct{})
+	for _, t := range targets {
+		ch, err := newChain([]MallTarget{t}, append(slices.Clone(bus), names...))
+		if err != nil {
+			return nil, err
+		}
+		uses[t.Item] = make(map[string]struct{})
+		needs[t.Item] = make(map[string]struct{})
+		for _, step := range ch.Steps {
+			needs[t.Item][step.Target] = struct{}{}
+			if step.Process == nil {
+				continue
+			}
+			for con := range step.Process.Consumes {
+				if slices.Contains(bus, con) && !slices.Contains(inputs[t.Item], con) {
+					inputs[t.Item] = append(inputs[t.Item], con)
+				}
+				if slices.Contains(names, con) && con != t.Item {
+					uses[t.Item][con] = struct{}{}
+				}
+			}
+		}
+		slices.Sort(inputs[t.Item])
+	}
+
+	// Build targets before anything that uses them, staying with the current group where possible
+	placed := make(map[string]struct{})
+	var lastInputs []string
+	for len(mall.Order) < len(names) {
+		var candidates []string
+		for _, name := range names {
+			if _, ok := placed[name]; ok {
+				continue
+			}
+			ready := true
+			for other := range uses[name] {
+				if _, done := placed[other]; !done {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				candidates = append(candidates, name)
+			}
+		}
+		if len(candidates) == 0 {
+			// Targets that need each other can't be ordered, so just take the rest as they come
+			for _, name := range names {
+				if _, ok := placed[name]; !ok {
+					candidates = append(candidates, name)
+				}
+			}
+		}
+		next := slices.MinFunc(candidates, func(a, b string) int {
+			return cmp.Or(
+				compareBool(!slices.Equal(inputs[a], lastInputs), !slices.Equal(inputs[b], lastInputs)),
+				cmp.Compare(len(inputs[a]), len(inputs[b])),
+				cmp.Compare(strings.Join(inputs[a], "\x00"), strings.Join(inputs[b], "\x00")),
+				cmp.Compare(a, b),
+			)
+		})
+		mall.Order = append(mall.Order, next)
+		placed[next] = struct{}{}
+		lastInputs = inputs[next]
+	}
+
+	// Group targets by their bus inputs, in the order the groups are first built
+	groupOf := make(map[string]int)
+	for _, name := range mall.Order {
+		key := strings.Join(inputs[name], "\x00")
+		idx := slices.IndexFunc(mall.Groups, func(g MallGroup) bool {
+			return strings.Join(g.Inputs, "\x00") == key
+		})
+		if idx < 0 {
+			mall.Groups = append(mall.Groups, MallGroup{Inputs: inputs[name]})
+			idx = len(mall.Groups) - 1
+		}
+		mall.Groups[idx].Targets = append(mall.Groups[idx].Targets, name)
+		groupOf[name] = idx
+	}
+
+	// Each step goes to the group of the target it is for, or else the first target built that needs it
+	for _, step := range mall.Chain.Steps {
+		owner, isTarget := groupOf[step.Target]
+		if !isTarget {
+			for _, name := range mall.Order {
+				if _, ok := needs[name][step.Target]; ok {
+					owner = groupOf[name]
+					break
+				}
+			}
+		}
+		mall.Groups[owner].Steps = append(mall.Groups[owner].Steps, step)
+	}
+	return mall, nil
+}
+
+// String formats the mall plan for display, one group at a time
+func (m *Mall) String() string {
+	return m.StringWithOpts()
+}
+
+// StringWithOpts formats the mall plan with the given options
+func (m *Mall) StringWithOpts(opts ...StringOption) string {
+	sb := strings.Builder{}
+	for i, g := range m.Groups {
+		from := "no bus inputs"
+		if len(g.Inputs) > 0 {
+			from = "from bus: " + strings.Join(g.Inputs, ", ")
+		}
+		sb.WriteString(fmt.Sprintf("Group %d: %s (%s)\n", i+1, strings.Join(g.Targets, ", "), from))
+		for _, step := range g.Steps {
+			sb.WriteString("  " + step.StringWithOpts(append(slices.Clone(opts), WithBuildings())...) + "\n")
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString("Construction order:\n")
+	for i, name := range m.Order {
+		sb.WriteString(fmt.Sprintf("  %d. %s\n", i+1, name))
+	}
+	if len(m.BusDemand) > 0 {
+		sb.WriteString("\nBus demand:\n")
+		for _, item := range slices.Sorted(maps.Keys(m.BusDemand)) {
+			sb.WriteString(fmt.Sprintf("  %s: %s\n", item, FormatRate(m.BusDemand[item], opts...)))
+		}
+	}
+	return sb.String()
+}
+
+// compareBool orders false before true
+func compareBool(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case a:
+		return 1
+	default:
+		return -1
+	}
+}
@@ -0,0 +1,136 @@
+package dyson
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+var mallTestYAMLData = `
+facilities:
+  assembler:
+    Assembling Machine: 1
+processes:
+  - makes: { Iron Ingot: 1 }
+    time: 1
+    facility: [ assembler ]
+  - makes: { Copper Ingot: 1 }
+    time: 1
+    facility: [ assembler ]
+  - makes: { Stone Brick: 1 }
+    time: 1
+    facility: [ assembler ]
+  - makes: { Gear: 1 }
+    consumes: { Iron Ingot: 1 }
+    time: 1
+    facility: [ assembler ]
+  - makes: { Circuit Board: 2 }
+    consumes: { Iron Ingot: 2, Copper Ingot: 1 }
+    time: 1
+    facility: [ assembler ]
+  - makes: { Belt: 3 }
+    consumes: { Gear: 1, Iron Ingot: 2 }
+    time: 1
+    facility: [ assembler ]
+  - makes: { Sorter: 1 }
+    consumes: { Circuit Board: 1, Iron Ingot: 1 }
+    time: 1
+    facility: [ assembler ]
+  - makes: { Fast Sorter: 1 }
+    consumes: { Sorter: 1, Gear: 2 }
+    time: 1
+    facility: [ assembler ]
+  - makes: { Depot: 1 }
+    consumes: { Iron Ingot: 4, Stone Brick: 4 }
+    time: 1
+    facility: [ assembler ]
+`
+
+func TestDataFile_PlanMall(t *testing.T) {
+	df, err := LoadData([]byte(mallTestYAMLData))
+	if err != nil {
+		t.Fatalf("Failed to load test data: %v", err)
+	}
+	bus := []string{"Iron Ingot", "Copper Ingot", "Stone Brick"}
+	mall, err := df.PlanMall([]MallTarget{
+		{Item: "Fast Sorter", Rate: 1},
+		{Item: "Depot", Rate: 0.5},
+		{Item: "Sorter", Rate: 1},
+		{Item: "Belt", Rate: 3},
+	}, bus)
+	if err != nil {
+		t.Fatalf("PlanMall() failed: %v", err)
+	}
+
+	// Sorter has to be built before Fast Sorter, which uses it, even though Fast Sorter shares Belt's group
+	wantOrder := []string{"Belt", "Sorter", "Fast Sorter", "Depot"}
+	if !slices.Equal(mall.Order, wantOrder) {
+		t.Errorf("Order = %v, want %v", mall.Order, wantOrder)
+	}
+
+	var groups []string
+	for _, g := range mall.Groups {
+		var steps []string
+		for _, s := range g.Steps {
+			steps = append(steps, s.Target)
+		}
+		groups = append(groups, strings.Join(g.Inputs, "+")+": "+strings.Join(g.Targets, ", ")+
+			" ("+strings.Join(steps, ", ")+")")
+	}
+	wantGroups := []string{
+		"Iron Ingot: Belt, Fast Sorter (Fast Sorter, Belt, Gear)",
+		"Copper Ingot+Iron Ingot: Sorter (Sorter, Circuit Board)",
+		"Iron Ingot+Stone Brick: Depot (Depot)",
+	}
+	if !slices.Equal(groups, wantGroups) {
+		t.Errorf("Groups:\n%s\nwant:\n%s", strings.Join(groups, "\n"), strings.Join(wantGroups, "\n"))
+	}
+
+	// Iron goes to belts (2), gears (1 for belts + 2 for fast sorters), sorters (2, including the ones fast sorters
+	// use), circuit boards (2) and depots (2)
+	want := map[string]float64{"Iron Ingot": 2 + 3 + 2 + 2 + 2, "Copper Ingot": 1, "Stone Brick": 2}
+	for item, rate := range want {
+		if got := mall.BusDemand[item]; got != rate {
+			t.Errorf("BusDemand[%s] = %v, want %v", item, got, rate)
+		}
+	}
+
+	out := mall.String()
+	for _, s := range []string{
+		"Group 1: Belt, Fast Sorter (from bus: Iron Ingot)\n",
+		"  Gear (3/s): Iron Ingot [3 Assembling Machine]\n",
+		"Construction order:\n  1. Belt\n  2. Sorter\n",
+		"Bus demand:\n  Copper Ingot: 1/s\n",
+	} {
+		if !strings.Contains(out, s) {
+			t.Errorf("String() is missing %q:\n%s", s, out)
+		}
+	}
+}
+
+func TestDataFile_PlanMallErrors(t *testing.T) {
+	df, err := LoadData([]byte(mallTestYAMLData))
+	if err != nil {
+		t.Fatalf("Failed to load test data: %v", err)
+	}
+	tests := []struct {
+		name    string
+		targets []MallTarget
+		errMsg  string
+	}{
+		{name: "no targets", errMsg: "no mall targets given"},
+		{name: "duplicate target", targets: []MallTarget{{Item: "Belt", Rate: 1}, {Item: "Belt", Rate: 1}},
+			errMsg: "duplicate mall target: Belt"},
+		{name: "target on bus", targets: []MallTarget{{Item: "Iron Ingot", Rate: 1}}, errMsg: "already on the bus"},
+		{name: "target without a rate", targets: []MallTarget{{Item: "Belt"}}, errMsg: "mall target Belt has no rate"},
+		{name: "unknown target", targets: []MallTarget{{Item: "Widget", Rate: 1}}, errMsg: "Widget"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := df.PlanMall(tt.targets, []string{"Iron Ingot"})
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("PlanMall() error = %v, want error containing %q", err, tt.errMsg)
+			}
+		})
+	}
+}
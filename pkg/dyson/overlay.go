@@ -0,0 +1,290 @@
+package dyson
+
+import (
+	"fmt"
+	yaml "gopkg.in/yaml.v3"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// DataLayer is one data file in a stack of layered data files
+type DataLayer struct {
+	Name string
+	Data []byte
+}
+
+type overlayData struct {
+	Overlay      bool                          `yaml:"overlay"`
+	Facilities   map[string]map[string]float64 `yaml:"facilities"`
+	Power        map[string]float64            `yaml:"power"`
+	Items        map[string]Item               `yaml:"items"`
+	Technologies map[string]Technology         `yaml:"technologies"`
+	Processes    []overlayProcess              `yaml:"processes"`
+
+	facilityPos map[string]Position
+	powerPos    map[string]Position
+}
+
+// overlayProcess is a process entry in an overlay file.  Fields that are not given leave the matching process
+// unchanged.
+type overlayProcess struct {
+	Makes    map[string]int `yaml:"makes"`
+	Consumes map[string]int `yaml:"consumes"`
+	Time     *float64       `yaml:"time"`
+	Facility []string       `yaml:"facility"`
+	Special  *bool          `yaml:"special"`
+	Tech     string         `yaml:"tech"`
+	Disabled bool           `yaml:"disabled"`
+
+	pos Position
+}
+
+type layerChange struct {
+	layer string
+	value string
+}
+
+// LoadDataLayers loads a stack of data files in order.  A file that sets "overlay: true" is merged into the layers
+// before it: its facilities and power add buildings or change their speeds and power draw, its items and technologies
+// replace the existing entries of the same name, and its processes either modify the existing process that makes and
+// consumes the same items, disable it with "disabled: true", or add a new process.  Only the first file may be a full
+// data file, since a later one would silently throw away everything before it.  If two overlays make different changes
+// to the same thing, this is reported as a conflict.  The result is always validated.
+func LoadDataLayers(layers ...DataLayer) (*DataFile, error) {
+	var df *DataFile
+	changes := make(map[string]layerChange)
+	for _, layer := range layers {
+		var root yaml.Node
+		err := yaml.Unmarshal(layer.Data, &root)
+		if err != nil {
+			return nil, fmt.Errorf("%s: could not parse data: %w", layer.Name, err)
+		}
+		var od overlayData
+		err = root.Decode(&od)
+		if err != nil {
+			return nil, fmt.Errorf("%s: could not parse data: %w", layer.Name, err)
+		}
+		if !od.Overlay {
+			if df != nil {
+				return nil, fmt.Errorf("%s: only the first data file can be a full data file, later ones must set "+
+					"\"overlay: true\"", layer.Name)
+			}
+			df, err = loadNamedData(layer.Name, layer.Data)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", layer.Name, err)
+			}
+			continue
+		}
+		if df == nil {
+			return nil, fmt.Errorf("%s: overlay has no data to apply to", layer.Name)
+		}
+		od.recordPositions(layer.Name, &root)
+		err = df.applyOverlay(layer.Name, &od, changes)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if df == nil {
+		return nil, fmt.Errorf("no data files given")
+	}
+	df.index()
+	err := df.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("data is invalid: %w", err)
+	}
+	return df, nil
+}
+
+// IsOverlay reports whether a data file is an overlay, to be merged into the data before it, rather than a full data
+// file
+func IsOverlay(data []byte) (bool, error) {
+	var od struct {
+		Overlay bool `yaml:"overlay"`
+	}
+	err := yaml.Unmarshal(data, &od)
+	if err != nil {
+		return false, fmt.Errorf("could not parse data: %w", err)
+	}
+	return od.Overlay, nil
+}
+
+func (df *DataFile) applyOverlay(layer string, od *overlayData, changes map[string]layerChange) error {
+	record := func(key string, what string, value string) error {
+		prev, ok := changes[key]
+		if ok && prev.layer != layer && prev.value != value {
+			return fmt.Errorf("conflicting changes to %s: %s in %s, but %s in %s", what, prev.value, prev.layer,
+				value, layer)
+		}
+		changes[key] = layerChange{layer: layer, value: value}
+		return nil
+	}
+
+	for _, facType := range slices.Sorted(maps.Keys(od.Facilities)) {
+		if df.Facilities == nil {
+			df.Facilities = make(map[string]map[string]float64)
+		}
+		if df.Facilities[facType] == nil {
+			df.Facilities[facType] = make(map[string]float64)
+		}
+		for _, fac := range slices.Sorted(maps.Keys(od.Facilities[facType])) {
+			speed := od.Facilities[facType][fac]
+			err := record("facility\x00"+facType+"\x00"+fac, "facility "+fac, fmt.Sprintf("speed %g", speed))
+			if err != nil {
+				return err
+			}
+			df.Facilities[facType][fac] = speed
+			df.facilityPos[facType+"\x00"+fac] = od.facilityPos[facType+"\x00"+fac]
+		}
+	}
+
+	for _, building := range slices.Sorted(maps.Keys(od.Power)) {
+		power := od.Power[building]
+		err := record("power\x00"+building, "power of "+building, fmt.Sprintf("%g kW", power))
+		if err != nil {
+			return err
+		}
+		if df.Power == nil {
+			df.Power = make(map[string]float64)
+		}
+		df.Power[building] = power
+		df.powerPos[building] = od.powerPos[building]
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(od.Items)) {
+		item := od.Items[name]
+		err := record("item\x00"+name, "item "+name, describeItem(item))
+		if err != nil {
+			return err
+		}
+		if df.Items == nil {
+			df.Items = make(map[string]Item)
+		}
+		df.Items[name] = item
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(od.Technologies)) {
+		tech := od.Technologies[name]
+		err := record("tech\x00"+name, "technology "+name, describeTech(tech))
+		if err != nil {
+			return err
+		}
+		if df.Technologies == nil {
+			df.Technologies = make(map[string]Technology)
+		}
+		df.Technologies[name] = tech
+	}
+
+	for _, op := range od.Processes {
+		if len(op.Makes) == 0 {
+			return fmt.Errorf("%s: process does not make anything", op.pos)
+		}
+		sig := processSignature(op.Makes, op.Consumes)
+		desc := describeProcess(op.Makes, op.Consumes)
+		idx := slices.IndexFunc(df.Processes, func(p Process) bool {
+			return processSignature(p.Makes, p.Consumes) == sig
+		})
+
+		if op.Disabled {
+			if idx < 0 {
+				return fmt.Errorf("%s: no %s to disable", op.pos, desc)
+			}
+			err := record(sig, desc, "disabled")
+			if err != nil {
+				return err
+			}
+			df.Processes = slices.Delete(df.Processes, idx, idx+1)
+			continue
+		}
+
+		err := record(sig, desc, "enabled")
+		if err != nil {
+			return err
+		}
+		if idx < 0 {
+			df.Processes = append(df.Processes, Process{
+				Makes:    make(map[string]int),
+				Consumes: make(map[string]int),
+			})
+			idx = len(df.Processes) - 1
+		}
+		proc := &df.Processes[idx]
+		proc.pos = op.pos
+		for _, item := range slices.Sorted(maps.Keys(op.Makes)) {
+			err = record(sig+"\x00makes\x00"+item, desc, fmt.Sprintf("makes %d %s", op.Makes[item], item))
+			if err != nil {
+				return err
+			}
+			proc.Makes[item] = op.Makes[item]
+		}
+		if len(op.Consumes) > 0 && proc.Consumes == nil {
+			proc.Consumes = make(map[string]int)
+		}
+		for _, item := range slices.Sorted(maps.Keys(op.Consumes)) {
+			err = record(sig+"\x00consumes\x00"+item, desc, fmt.Sprintf("consumes %d %s", op.Consumes[item], item))
+			if err != nil {
+				return err
+			}
+			proc.Consumes[item] = op.Consumes[item]
+		}
+		if op.Time != nil {
+			err = record(sig+"\x00time", desc, fmt.Sprintf("time %g", *op.Time))
+			if err != nil {
+				return err
+			}
+			proc.Time = *op.Time
+		}
+		if len(op.Facility) > 0 {
+			err = record(sig+"\x00facility", desc, "facility "+strings.Join(op.Facility, ", "))
+			if err != nil {
+				return err
+			}
+			proc.Facility = op.Facility
+		}
+		if op.Special != nil {
+			err = record(sig+"\x00special", desc, fmt.Sprintf("special %t", *op.Special))
+			if err != nil {
+				return err
+			}
+			proc.Special = *op.Special
+		}
+		if op.Tech != "" {
+			err = record(sig+"\x00tech", desc, "tech "+op.Tech)
+			if err != nil {
+				return err
+			}
+			proc.Tech = op.Tech
+		}
+	}
+	return nil
+}
+
+// recordPositions remembers where processes and facilities were defined in an overlay file
+func (od *overlayData) recordPositions(name string, root *yaml.Node) {
+	// Reuse the data file position logic, since overlays have the same layout
+	var df DataFile
+	df.Items = od.Items
+	df.Technologies = od.Technologies
+	df.Processes = make([]Process, len(od.Processes))
+	df.recordPositions(name, root)
+	od.facilityPos = df.facilityPos
+	od.powerPos = df.powerPos
+	for i := range od.Processes {
+		od.Processes[i].pos = df.Processes[i].pos
+	}
+}
+
+// processSignature identifies a process by the items it makes and consumes, ignoring the counts
+func processSignature(makes map[string]int, consumes map[string]int) string {
+	return strings.Join(slices.Sorted(maps.Keys(makes)), "\x00") + "\x01" +
+		strings.Join(slices.Sorted(maps.Keys(consumes)), "\x00")
+}
+
+// describeProcess returns a human-readable name for a process, e.g. "process making Gear from Iron Ingot"
+func describeProcess(makes map[string]int, consumes map[string]int) string {
+	desc := "process making " + strings.Join(slices.Sorted(maps.Keys(makes)), ", ")
+	if len(consumes) > 0 {
+		desc += " from " + strings.Join(slices.Sorted(maps.Keys(consumes)), ", ")
+	}
+	return desc
+}
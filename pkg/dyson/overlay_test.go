@@ -0,0 +1,305 @@
+package dyson
+
+import (
+	"strings"
+	"testing"
+)
+
+var testOverlayData = `
+overlay: true
+facilities:
+  smelter:
+    Arc Smelter: 1.5
+    Negentropy Smelter: 3
+processes:
+  - makes:
+      Iron Ingot: 1
+    consumes:
+      Iron Ore: 1
+    time: 0.5
+  - makes:
+      Gear: 1
+    consumes:
+      Iron Ingot: 1
+    disabled: true
+  - makes:
+      Gear: 2
+    consumes:
+      Steel: 1
+    time: 1
+    facility: [ assembler ]
+  - makes:
+      Steel: 1
+    consumes:
+      Iron Ingot: 3
+    time: 3
+    facility: [ smelter ]
+`
+
+func TestLoadDataLayers(t *testing.T) {
+	df, err := LoadDataLayers(
+		DataLayer{Name: "base.yml", Data: []byte(testYAMLData)},
+		DataLayer{Name: "overlay.yml", Data: []byte(testOverlayData)},
+	)
+	if err != nil {
+		t.Fatalf("LoadDataLayers() failed: %v", err)
+	}
+
+	if df.Facilities["smelter"]["Arc Smelter"] != 1.5 {
+		t.Errorf("Arc Smelter speed = %v, want 1.5", df.Facilities["smelter"]["Arc Smelter"])
+	}
+	if df.Facilities["smelter"]["Negentropy Smelter"] != 3 {
+		t.Errorf("Negentropy Smelter speed = %v, want 3", df.Facilities["smelter"]["Negentropy Smelter"])
+	}
+	if df.Facilities["smelter"]["Plane Smelter"] != 2 {
+		t.Errorf("Plane Smelter speed = %v, want 2 (unchanged)", df.Facilities["smelter"]["Plane Smelter"])
+	}
+
+	ingots := df.procsByTarget["Iron Ingot"]
+	if len(ingots) != 1 {
+		t.Fatalf("expected 1 Iron Ingot process, got %d", len(ingots))
+	}
+	if ingots[0].Time != 0.5 {
+		t.Errorf("Iron Ingot time = %v, want 0.5", ingots[0].Time)
+	}
+	if len(ingots[0].Facility) != 1 || ingots[0].Facility[0] != "smelter" {
+		t.Errorf("Iron Ingot facility = %v, want unchanged [smelter]", ingots[0].Facility)
+	}
+	if ingots[0].pos.String() != "overlay.yml:8:5" {
+		t.Errorf("Iron Ingot position = %q, want %q", ingots[0].pos, "overlay.yml:8:5")
+	}
+
+	gears := df.procsByTarget["Gear"]
+	if len(gears) != 1 {
+		t.Fatalf("expected 1 Gear process, got %d", len(gears))
+	}
+	if _, ok := gears[0].Consumes["Steel"]; !ok {
+		t.Errorf("Gear process should be the one added by the overlay, got %v", gears[0].Consumes)
+	}
+
+	circuits := df.procsByTarget["Circuit Board"]
+	if len(circuits) != 1 || circuits[0].pos.File != "base.yml" {
+		t.Errorf("Circuit Board process should come from base.yml, got %v", circuits)
+	}
+}
+
+func TestIsOverlay(t *testing.T) {
+	tests := map[string]bool{
+		testYAMLData:    false,
+		testOverlayData: true,
+	}
+	for data, want := range tests {
+		got, err := IsOverlay([]byte(data))
+		if err != nil {
+			t.Fatalf("IsOverlay() failed: %v", err)
+		}
+		if got != want {
+			t.Errorf("IsOverlay() = %v, want %v", got, want)
+		}
+	}
+	_, err := IsOverlay([]byte(invalidYAMLData))
+	if err == nil {
+		t.Error("IsOverlay() should fail for invalid YAML")
+	}
+}
+
+func TestLoadDataLayers_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		layers []DataLayer
+		errMsg string
+	}{
+		{
+			name:   "no layers",
+			errMsg: "no data files",
+		},
+		{
+			name:   "overlay without base",
+			layers: []DataLayer{{Name: "overlay.yml", Data: []byte(testOverlayData)}},
+			errMsg: "overlay.yml: overlay has no data to apply to",
+		},
+		{
+			name: "full data file after the first",
+			layers: []DataLayer{
+				{Name: "base.yml", Data: []byte(testYAMLData)},
+				{Name: "other.yml", Data: []byte(chainTestYAMLData)},
+			},
+			errMsg: "other.yml: only the first data file can be a full data file",
+		},
+		{
+			name: "invalid single file",
+			layers: []DataLayer{{Name: "bad.yml", Data: []byte(`
+facilities:
+  smelter:
+    Arc Smelter: 0
+processes: []
+`)}},
+			errMsg: "bad.yml:4:5: facility rate is zero: Arc Smelter",
+		},
+		{
+			name: "invalid YAML",
+			layers: []DataLayer{
+				{Name: "base.yml", Data: []byte(testYAMLData)},
+				{Name: "bad.yml", Data: []byte(invalidYAMLData)},
+			},
+			errMsg: "bad.yml",
+		},
+		{
+			name: "disable missing process",
+			layers: []DataLayer{
+				{Name: "base.yml", Data: []byte(testYAMLData)},
+				{Name: "overlay.yml", Data: []byte(`
+overlay: true
+processes:
+  - makes: { Gear: 1 }
+    consumes: { Copper Ingot: 1 }
+    disabled: true
+`)},
+			},
+			errMsg: "overlay.yml:4:5: no process making Gear from Copper Ingot to disable",
+		},
+		{
+			name: "conflicting overlays",
+			layers: []DataLayer{
+				{Name: "base.yml", Data: []byte(testYAMLData)},
+				{Name: "a.yml", Data: []byte(testOverlayData)},
+				{Name: "b.yml", Data: []byte(`
+overlay: true
+processes:
+  - makes: { Iron Ingot: 1 }
+    consumes: { Iron Ore: 1 }
+    time: 2
+`)},
+			},
+			errMsg: "time 0.5 in a.yml, but time 2 in b.yml",
+		},
+		{
+			name: "re-enabling a disabled process",
+			layers: []DataLayer{
+				{Name: "base.yml", Data: []byte(testYAMLData)},
+				{Name: "a.yml", Data: []byte(testOverlayData)},
+				{Name: "b.yml", Data: []byte(`
+overlay: true
+processes:
+  - makes: { Gear: 1 }
+    consumes: { Iron Ingot: 1 }
+    time: 1
+    facility: [ assembler ]
+`)},
+			},
+			errMsg: "disabled in a.yml, but enabled in b.yml",
+		},
+		{
+			name: "invalid merged result",
+			layers: []DataLayer{
+				{Name: "base.yml", Data: []byte(testYAMLData)},
+				{Name: "overlay.yml", Data: []byte(`
+overlay: true
+processes:
+  - makes: { Widget: 1 }
+    consumes: { Unobtainium: 1 }
+    time: 1
+    facility: [ assembler ]
+`)},
+			},
+			errMsg: "overlay.yml:4:5: item cannot be made: Unobtainium (used by process making Widget from Unobtainium)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadDataLayers(tt.layers...)
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("LoadDataLayers() error = %v, want error containing %q", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestLoadDataLayers_Power(t *testing.T) {
+	df, err := LoadDataLayers(
+		DataLayer{Name: "base.yml", Data: []byte(powerTestYAMLData)},
+		DataLayer{Name: "overlay.yml", Data: []byte("overlay: true\npower:\n  Arc Smelter: 400\n")},
+	)
+	if err != nil {
+		t.Fatalf("LoadDataLayers() failed: %v", err)
+	}
+	if df.Power["Arc Smelter"] != 400 || df.Power["Plane Smelter"] != 1440 {
+		t.Errorf("Power = %v, want Arc Smelter changed and the rest kept", df.Power)
+	}
+
+	_, err = LoadDataLayers(
+		DataLayer{Name: "base.yml", Data: []byte(powerTestYAMLData)},
+		DataLayer{Name: "a.yml", Data: []byte("overlay: true\npower:\n  Arc Smelter: 400\n")},
+		DataLayer{Name: "b.yml", Data: []byte("overlay: true\npower:\n  Arc Smelter: 300\n")},
+	)
+	if err == nil || !strings.Contains(err.Error(), "conflicting changes to power of Arc Smelter") {
+		t.Errorf("LoadDataLayers() error = %v, want a power conflict", err)
+	}
+}
+
+func TestLoadDataLayers_Items(t *testing.T) {
+	df, err := LoadDataLayers(
+		DataLayer{Name: "base.yml", Data: []byte(itemsTestYAMLData)},
+		DataLayer{Name: "overlay.yml", Data: []byte("overlay: true\nitems:\n  Gear: { category: component, stack: 200 }\n")},
+	)
+	if err != nil {
+		t.Fatalf("LoadDataLayers() failed: %v", err)
+	}
+	item, ok := df.Item("Gear")
+	if !ok || item.Stack != 200 || item.pos.String() != "overlay.yml:3:3" {
+		t.Errorf("Item(Gear) = %+v, %v", item, ok)
+	}
+	if _, ok := df.Item("Iron Ore"); !ok {
+		t.Error("items from the base layer should be kept")
+	}
+
+	_, err = LoadDataLayers(
+		DataLayer{Name: "base.yml", Data: []byte(itemsTestYAMLData)},
+		DataLayer{Name: "a.yml", Data: []byte("overlay: true\nitems:\n  Gear: { category: component, stack: 200 }\n")},
+		DataLayer{Name: "b.yml", Data: []byte("overlay: true\nitems:\n  Gear: { category: component, stack: 100 }\n")},
+	)
+	if err == nil || !strings.Contains(err.Error(), "conflicting changes to item Gear") {
+		t.Errorf("LoadDataLayers() error = %v, want an item conflict", err)
+	}
+}
+
+func TestLoadDataLayers_Technologies(t *testing.T) {
+	df, err := LoadDataLayers(
+		DataLayer{Name: "base.yml", Data: []byte(techTestYAMLData)},
+		DataLayer{Name: "overlay.yml", Data: []byte(`
+overlay: true
+technologies:
+  Gears: { cost: { Iron Ingot: 5 } }
+processes:
+  - makes: { Gear: 1 }
+    consumes: { Iron Ingot: 1 }
+    tech: Basics
+`)},
+	)
+	if err != nil {
+		t.Fatalf("LoadDataLayers() failed: %v", err)
+	}
+	if len(df.Technologies["Gears"].Requires) != 0 || df.Technologies["Gears"].Cost["Iron Ingot"] != 5 {
+		t.Errorf("Gears technology = %+v, want it replaced", df.Technologies["Gears"])
+	}
+	restricted, err := df.WithResearched([]string{"Basics"})
+	if err != nil {
+		t.Fatalf("WithResearched() failed: %v", err)
+	}
+	if len(restricted.procsByTarget["Gear"]) != 1 {
+		t.Error("the overlay should have moved the Gear process to the Basics technology")
+	}
+}
+
+func TestLoadDataLayers_SameChangeTwice(t *testing.T) {
+	// Two overlays making the same change don't conflict
+	_, err := LoadDataLayers(
+		DataLayer{Name: "base.yml", Data: []byte(testYAMLData)},
+		DataLayer{Name: "a.yml", Data: []byte("overlay: true\nfacilities:\n  smelter:\n    Arc Smelter: 1.5\n")},
+		DataLayer{Name: "b.yml", Data: []byte("overlay: true\nfacilities:\n  smelter:\n    Arc Smelter: 1.5\n")},
+	)
+	if err != nil {
+		t.Errorf("LoadDataLayers() unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,169 @@
+package dyson
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	yaml "gopkg.in/yaml.v3"
+	"io"
+	"maps"
+	"slices"
+)
+
+// Plan describes a whole factory: what it should produce, what it is supplied with, and how it is built.  Items it
+// has are supplied from outside, and used but not made; excluded items are never made or used.
+type Plan struct {
+	Targets      []PlanTarget        `yaml:"targets"`
+	Have         []string            `yaml:"have,omitempty"`
+	Exclude      []string            `yaml:"exclude,omitempty"`
+	Recipes      map[string][]string `yaml:"recipes,omitempty"`
+	Buildings    map[string]string   `yaml:"buildings,omitempty"`
+	Proliferator Proliferator        `yaml:"proliferator,omitempty"`
+	Built        map[string]float64  `yaml:"built,omitempty"` // buildings placed for each item, for bottleneck analysis
+}
+
+// PlanTarget is an item the factory produces.  The rate can be given either in items per second or as a number of
+// factories.
+type PlanTarget struct {
+	Item      string  `yaml:"item"`
+	Rate      float64 `yaml:"rate,omitempty"`
+	Factories float64 `yaml:"factories,omitempty"`
+}
+
+// LoadPlan parses a plan file.  Unknown keys are rejected so that typos don't silently change the plan.
+func LoadPlan(data []byte) (*Plan, error) {
+	var p Plan
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	err := dec.Decode(&p)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("could not parse plan: %w", err)
+	}
+	return &p, nil
+}
+
+// Marshal writes the plan in the same YAML layout it is loaded from
+func (p *Plan) Marshal() ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	err := enc.Encode(p)
+	if err != nil {
+		return nil, fmt.Errorf("could not encode plan: %w", err)
+	}
+	err = enc.Close()
+	if err != nil {
+		return nil, fmt.Errorf("could not encode plan: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Validate checks the plan against a data file
+func (p *Plan) Validate(df *DataFile) error {
+	if len(p.Targets) == 0 {
+		return fmt.Errorf("plan has no targets")
+	}
+	seen := make(map[string]struct{}, len(p.Targets))
+	for _, t := range p.Targets {
+		if t.Item == "" {
+			return fmt.Errorf("plan target has no item")
+		}
+		// Each target's rate is what the chain delivers of it, so a second one would be counted twice
+		if _, ok := seen[t.Item]; ok {
+			return fmt.Errorf("target %s is given more than once", t.Item)
+		}
+		seen[t.Item] = struct{}{}
+		if len(df.procsByTarget[t.Item]) == 0 {
+			return fmt.Errorf("unknown target item: %w", df.noProcessError(t.Item))
+		}
+		if t.Rate != 0 && t.Factories != 0 {
+			return fmt.Errorf("target %s has both a rate and a factory count", t.Item)
+		}
+		if t.Rate < 0 || t.Factories < 0 {
+			return fmt.Errorf("target %s has a negative rate", t.Item)
+		}
+		if slices.Contains(p.Exclude, t.Item) {
+			return fmt.Errorf("target %s is excluded", t.Item)
+		}
+	}
+	for _, facType := range slices.Sorted(maps.Keys(p.Buildings)) {
+		facs, ok := df.Facilities[facType]
+		if !ok {
+			return fmt.Errorf("unknown facility type: %s", facType)
+		}
+		if _, ok := facs[p.Buildings[facType]]; !ok {
+			return fmt.Errorf("unknown building %s for facility type %s", p.Buildings[facType], facType)
+		}
+	}
+	for _, item := range slices.Sorted(maps.Keys(p.Built)) {
+		if p.Built[item] < 0 {
+			return fmt.Errorf("built count for %s is negative", item)
+		}
+	}
+	return p.Proliferator.Validate()
+}
+
+// TargetItems returns the items the plan produces, sorted, whether they are given as a rate or a factory count
+func (p *Plan) TargetItems() []string {
+	var items []string
+	for _, t := range p.Targets {
+		if !slices.Contains(items, t.Item) {
+			items = append(items, t.Item)
+		}
+	}
+	slices.Sort(items)
+	return items
+}
+
+// ChainOptions returns the chain options that implement the plan's exclusions, and its recipe, building and
+// proliferator choices
+func (p *Plan) ChainOptions() []ChainOption {
+	var opts []ChainOption
+	if len(p.Exclude) > 0 {
+		opts = append(opts, WithExcludedItems(p.Exclude...))
+	}
+	for _, item := range slices.Sorted(maps.Keys(p.Recipes)) {
+		opts = append(opts, WithRecipe(item, p.Recipes[item]))
+	}
+	for _, facType := range slices.Sorted(maps.Keys(p.Buildings)) {
+		opts = append(opts, WithBuilding(facType, p.Buildings[facType]))
+	}
+	if p.Proliferator.Level > 0 {
+		opts = append(opts, WithProliferator(p.Proliferator))
+	}
+	return opts
+}
+
+// RunPlan builds the full production chain described by a plan.  Any chain options given are applied after the
+// plan's own.
+func (df *DataFile) RunPlan(p *Plan, opts ...ChainOption) (*ProductionChain, error) {
+	err := p.Validate(df)
+	if err != nil {
+		return nil, err
+	}
+	var reqs []string
+	for _, t := range p.Targets {
+		reqs = append(reqs, t.Item)
+	}
+	pc := df.NewChain(reqs, append(p.ChainOptions(), opts...)...)
+	for _, t := range p.Targets {
+		rate := t.Rate
+		if t.Factories > 0 {
+			rate, err = pc.FactoriesToItemsPerSecond(t.Item, t.Factories)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if rate > 0 {
+			err = pc.SetRate(t.Item, rate)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	err = pc.FillChainExcluding(p.Have)
+	if err != nil {
+		return nil, err
+	}
+	return pc, nil
+}
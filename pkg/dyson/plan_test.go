@@ -0,0 +1,245 @@
+package dyson
+
+import (
+	"maps"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var testPlanData = `
+targets:
+  - item: Circuit Board
+    rate: 4
+  - item: Gear
+    factories: 2
+have: [ Copper Ingot ]
+buildings:
+  smelter: Plane Smelter
+proliferator:
+  level: 1
+  mode: speed
+`
+
+func TestLoadPlan(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{
+			name:    "valid plan",
+			data:    testPlanData,
+			wantErr: false,
+		},
+		{
+			name:    "empty plan",
+			data:    "",
+			wantErr: false,
+		},
+		{
+			name:    "unknown key",
+			data:    "targetz: []\n",
+			wantErr: true,
+		},
+		{
+			name:    "invalid YAML",
+			data:    invalidYAMLData,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := LoadPlan([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("LoadPlan() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && p == nil {
+				t.Error("LoadPlan() returned nil Plan without error")
+			}
+		})
+	}
+}
+
+func TestPlan_Validate(t *testing.T) {
+	df := getTestDataFile(t)
+
+	tests := []struct {
+		name   string
+		plan   Plan
+		errMsg string
+	}{
+		{
+			name: "valid plan",
+			plan: Plan{Targets: []PlanTarget{{Item: "Gear", Rate: 1}}},
+		},
+		{
+			name:   "no targets",
+			plan:   Plan{},
+			errMsg: "no targets",
+		},
+		{
+			name:   "unknown target",
+			plan:   Plan{Targets: []PlanTarget{{Item: "Nonexistent Item"}}},
+			errMsg: "unknown target item",
+		},
+		{
+			name:   "duplicate target",
+			plan:   Plan{Targets: []PlanTarget{{Item: "Gear", Rate: 1}, {Item: "Gear", Rate: 2}}},
+			errMsg: "target Gear is given more than once",
+		},
+		{
+			name:   "rate and factories",
+			plan:   Plan{Targets: []PlanTarget{{Item: "Gear", Rate: 1, Factories: 1}}},
+			errMsg: "both a rate and a factory count",
+		},
+		{
+			name: "excluded target",
+			plan: Plan{
+				Targets: []PlanTarget{{Item: "Gear"}},
+				Exclude: []string{"Gear"},
+			},
+			errMsg: "target Gear is excluded",
+		},
+		{
+			name: "unknown facility type",
+			plan: Plan{
+				Targets:   []PlanTarget{{Item: "Gear"}},
+				Buildings: map[string]string{"nonexistent": "Arc Smelter"},
+			},
+			errMsg: "unknown facility type",
+		},
+		{
+			name: "unknown building",
+			plan: Plan{
+				Targets:   []PlanTarget{{Item: "Gear"}},
+				Buildings: map[string]string{"smelter": "Plane Smelter"},
+			},
+			errMsg: "unknown building",
+		},
+		{
+			name: "negative built count",
+			plan: Plan{
+				Targets: []PlanTarget{{Item: "Gear"}},
+				Built:   map[string]float64{"Gear": -1},
+			},
+			errMsg: "built count for Gear is negative",
+		},
+		{
+			name: "invalid proliferator mode",
+			plan: Plan{
+				Targets:      []PlanTarget{{Item: "Gear"}},
+				Proliferator: Proliferator{Level: 2, Mode: "faster"},
+			},
+			errMsg: "invalid proliferator mode",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.plan.Validate(df)
+			if tt.errMsg == "" {
+				if err != nil {
+					t.Errorf("Plan.Validate() unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Plan.Validate() error = %v, want error containing %q", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestPlan_TargetItems(t *testing.T) {
+	// The same items are produced whether they are given as a rate or a factory count, in any order
+	a := Plan{Targets: []PlanTarget{{Item: "Gear", Rate: 1}, {Item: "Circuit Board", Factories: 2}}}
+	b := Plan{Targets: []PlanTarget{{Item: "Circuit Board", Rate: 4}, {Item: "Gear", Factories: 1}}}
+	want := []string{"Circuit Board", "Gear"}
+	for _, p := range []Plan{a, b} {
+		if got := p.TargetItems(); !reflect.DeepEqual(got, want) {
+			t.Errorf("TargetItems() = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestDataFile_RunPlan(t *testing.T) {
+	df, err := LoadData([]byte(testYAMLData))
+	if err != nil {
+		t.Fatalf("Failed to load test data: %v", err)
+	}
+	p, err := LoadPlan([]byte(testPlanData))
+	if err != nil {
+		t.Fatalf("LoadPlan() failed: %v", err)
+	}
+
+	pc, err := df.RunPlan(p)
+	if err != nil {
+		t.Fatalf("RunPlan() failed: %v", err)
+	}
+
+	// 2 Mk. II assemblers sped up by 25% make 2.5 Gears/s.  Circuit Board needs 4 Iron Ingots/s, plus 2.5 for
+	// Gear, and Copper Ingot is supplied from outside.
+	expected := map[string]struct {
+		rate      float64
+		building  string
+		factories float64
+	}{
+		"Circuit Board": {rate: 4, building: "Assembling Machine Mk. II", factories: 1.6},
+		"Gear":          {rate: 2.5, building: "Assembling Machine Mk. II", factories: 2},
+		"Iron Ingot":    {rate: 6.5, building: "Plane Smelter", factories: 2.6},
+		"Iron Ore":      {rate: 6.5, building: "Mining Machine", factories: 13},
+	}
+	if len(pc.Steps) != len(expected) {
+		t.Errorf("RunPlan() produced %d steps, want %d:\n%s", len(pc.Steps), len(expected), pc.String())
+	}
+	for _, step := range pc.Steps {
+		want, ok := expected[step.Target]
+		if !ok {
+			t.Errorf("RunPlan() produced unexpected step %s", step.Target)
+			continue
+		}
+		if step.Rate != want.rate {
+			t.Errorf("Step %s: rate = %.3f, want %.3f", step.Target, step.Rate, want.rate)
+		}
+		if step.Building != want.building {
+			t.Errorf("Step %s: building = %q, want %q", step.Target, step.Building, want.building)
+		}
+		if step.Factories() != want.factories {
+			t.Errorf("Step %s: factories = %.3f, want %.3f", step.Target, step.Factories(), want.factories)
+		}
+	}
+
+	if got, want := pc.Supplied(), map[string]float64{"Copper Ingot": 2}; !maps.Equal(got, want) {
+		t.Errorf("Supplied() = %v, want %v", got, want)
+	}
+
+	item, _ := pc.ProliferatorDemand()
+	if item != "Proliferator Mk. I" {
+		t.Errorf("ProliferatorDemand() item = %q, want %q", item, "Proliferator Mk. I")
+	}
+}
+
+func TestPlan_Marshal(t *testing.T) {
+	p, err := LoadPlan([]byte(testPlanData))
+	if err != nil {
+		t.Fatalf("Failed to load plan: %v", err)
+	}
+	data, err := p.Marshal()
+	if err != nil {
+		t.Fatalf("Failed to marshal plan: %v", err)
+	}
+	// Empty sections are left out
+	if strings.Contains(string(data), "exclude") || strings.Contains(string(data), "rate: 0") {
+		t.Errorf("Marshal() wrote empty sections:\n%s", data)
+	}
+	again, err := LoadPlan(data)
+	if err != nil {
+		t.Fatalf("Failed to load marshalled plan: %v\n%s", err, data)
+	}
+	if !reflect.DeepEqual(again, p) {
+		t.Errorf("LoadPlan(Marshal()) = %+v, want %+v", again, p)
+	}
+}
@@ -0,0 +1,213 @@
+package dyson
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Planner builds production chains from targets given the way the command line gives them, as item or item:rate, so
+// that other programs get exactly the same results as the CLI.  It turns its options into a Plan, and runs that.
+type Planner struct {
+	df        *DataFile
+	targets   []plannerTarget
+	err       error // the first target that couldn't be parsed
+	have      []string
+	exclude   []string
+	unit      RateUnit
+	factories bool
+	chainOpts []ChainOption
+	diff      plannerDiff
+}
+
+// plannerTarget is a target as it was given, before the planner's unit or factory mode is applied
+type plannerTarget struct {
+	item string
+	rate float64 // in the planner's rate unit or as a factory count, or 0 for none
+}
+
+// plannerDiff is the items a diff compares
+type plannerDiff struct {
+	oldItems, newItems     []string
+	excludeOld, excludeNew []string
+}
+
+// PlannerOption configures a Planner
+type PlannerOption func(*Planner)
+
+// PlanResult is a planned production chain
+type PlanResult struct {
+	Chain    *ProductionChain
+	Targets  []string           // targets in the order given
+	Rates    map[string]float64 // rate of each target given one, in items per second
+	Raw      map[string]float64 // raw resources the chain uses, in items per second
+	Supplied map[string]float64 // items the chain has and uses, in items per second
+	Power    float64            // working power of the chain's buildings, in kW
+	Unit     RateUnit           // unit the targets were given in, for showing the results
+	Diff     *Diff              // what the new items add and remove, only set by Diff
+}
+
+// NewPlanner creates a planner for the data file
+func (df *DataFile) NewPlanner(opts ...PlannerOption) *Planner {
+	p := &Planner{df: df, unit: PerSecond}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// WithTargets adds targets, each either an item name or item:rate.  A target that can't be parsed is reported when
+// the planner is run.
+func WithTargets(targets ...string) PlannerOption {
+	return func(p *Planner) {
+		for _, arg := range targets {
+			t, err := parseTarget(arg)
+			if err != nil {
+				if p.err == nil {
+					p.err = err
+				}
+				continue
+			}
+			p.targets = append(p.targets, t)
+		}
+	}
+}
+
+// WithTarget adds a target with a rate, in the planner's rate unit or as a factory count
+func WithTarget(item string, rate float64) PlannerOption {
+	return func(p *Planner) {
+		p.targets = append(p.targets, plannerTarget{item: item, rate: rate})
+	}
+}
+
+// WithHave gives items that are supplied from outside the chain, which it uses but doesn't make
+func WithHave(items ...string) PlannerOption {
+	return func(p *Planner) {
+		p.have = append(p.have, items...)
+	}
+}
+
+// WithExclude gives items that are never made or used: processes that need them are never chosen
+func WithExclude(items ...string) PlannerOption {
+	return func(p *Planner) {
+		p.exclude = append(p.exclude, items...)
+	}
+}
+
+// WithRateUnit sets the time unit that target rates are given in.  The default is items per second.
+func WithRateUnit(unit RateUnit) PlannerOption {
+	return func(p *Planner) {
+		p.unit = unit
+	}
+}
+
+// WithFactoryRates sets whether target rates count the factories that make them instead of items, in the building
+// the chain uses for each target
+func WithFactoryRates(factories bool) PlannerOption {
+	return func(p *Planner) {
+		p.factories = factories
+	}
+}
+
+// WithChainOptions passes options, such as recipe and building choices, to the chains the planner builds
+func WithChainOptions(opts ...ChainOption) PlannerOption {
+	return func(p *Planner) {
+		p.chainOpts = append(p.chainOpts, opts...)
+	}
+}
+
+// WithDiff sets the items Diff compares: what can be made from the old items, avoiding excludeOld, and what can be
+// made from the old and new items together, avoiding excludeNew
+func WithDiff(oldItems, newItems, excludeOld, excludeNew []string) PlannerOption {
+	return func(p *Planner) {
+		p.diff = plannerDiff{oldItems: oldItems, newItems: newItems, excludeOld: excludeOld, excludeNew: excludeNew}
+	}
+}
+
+// parseTarget parses a target given as item or item:rate
+func parseTarget(arg string) (plannerTarget, error) {
+	if !strings.Contains(arg, ":") {
+		return plannerTarget{item: arg}, nil
+	}
+	parts := strings.Split(arg, ":")
+	if len(parts) != 2 {
+		return plannerTarget{}, fmt.Errorf("invalid argument: %s", arg)
+	}
+	rate, err := strconv.ParseFloat(parts[1], 64)
+	if err != nil {
+		return plannerTarget{}, fmt.Errorf("invalid rate: %s", parts[1])
+	}
+	return plannerTarget{item: parts[0], rate: rate}, nil
+}
+
+// Plan returns the plan the planner runs: its targets, with rates converted to items per second or given as factory
+// counts, and the items it has and excludes
+func (p *Planner) Plan() (*Plan, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	plan := &Plan{Have: p.have, Exclude: p.exclude}
+	for _, t := range p.targets {
+		pt := PlanTarget{Item: t.item}
+		switch {
+		case t.rate == 0:
+		case p.factories:
+			pt.Factories = t.rate
+		default:
+			pt.Rate = p.unit.ToPerSecond(t.rate)
+		}
+		plan.Targets = append(plan.Targets, pt)
+	}
+	return plan, nil
+}
+
+// Chain builds and fills the production chain for the planner's targets
+func (p *Planner) Chain() (*PlanResult, error) {
+	plan, err := p.Plan()
+	if err != nil {
+		return nil, err
+	}
+	pc, err := p.df.RunPlan(plan, p.chainOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("error filling chain: %w", err)
+	}
+	res := &PlanResult{
+		Chain:    pc,
+		Rates:    make(map[string]float64),
+		Raw:      pc.RawResources(),
+		Supplied: pc.Supplied(),
+		Power:    pc.Power(),
+		Unit:     p.unit,
+	}
+	for _, t := range plan.Targets {
+		res.Targets = append(res.Targets, t.Item)
+		switch {
+		case t.Rate > 0:
+			res.Rates[t.Item] = t.Rate
+		case t.Factories > 0:
+			res.Rates[t.Item], err = pc.FactoriesToItemsPerSecond(t.Item, t.Factories)
+			if err != nil {
+				return nil, fmt.Errorf("error calculating rate: %w", err)
+			}
+		}
+	}
+	return res, nil
+}
+
+// DisplayOptions returns string options that show the result's rates in the unit its targets were given in, followed
+// by any other options given
+func (r *PlanResult) DisplayOptions(opts ...StringOption) []StringOption {
+	return append([]StringOption{WithDisplayUnit(r.Unit)}, opts...)
+}
+
+// Diff compares the items given by WithDiff, and returns a result with only the diff set.  A diff looks at everything
+// that can be made rather than building a chain, so the planner's targets, items it has and excludes, and chain
+// options don't apply to it.
+func (p *Planner) Diff() (*PlanResult, error) {
+	d := p.diff
+	diff, err := p.df.Diff(d.oldItems, d.newItems, d.excludeOld, d.excludeNew)
+	if err != nil {
+		return nil, err
+	}
+	return &PlanResult{Diff: diff, Unit: p.unit}, nil
+}
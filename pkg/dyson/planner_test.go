@@ -0,0 +1,234 @@
+package dyson
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPlanner_Chain(t *testing.T) {
+	df := getTestDataFile(t)
+
+	tests := []struct {
+		name    string
+		opts    []PlannerOption
+		targets []string
+		rates   map[string]float64
+		raw     map[string]float64
+		have    map[string]float64 // supplied items the chain uses, if any are given
+		missing []string           // items that must not have a step
+	}{
+		{
+			name:    "rate per second",
+			opts:    []PlannerOption{WithTargets("Gear:2")},
+			targets: []string{"Gear"},
+			rates:   map[string]float64{"Gear": 2},
+			raw:     map[string]float64{"Iron Ore": 2},
+		},
+		{
+			name:    "rate per minute",
+			opts:    []PlannerOption{WithTargets("Gear:60"), WithRateUnit(PerMinute)},
+			targets: []string{"Gear"},
+			rates:   map[string]float64{"Gear": 1},
+			raw:     map[string]float64{"Iron Ore": 1},
+		},
+		{
+			name:    "target with a numeric rate",
+			opts:    []PlannerOption{WithTarget("Gear", 0.5)},
+			targets: []string{"Gear"},
+			rates:   map[string]float64{"Gear": 0.5},
+			raw:     map[string]float64{"Iron Ore": 0.5},
+		},
+		{
+			// A Gear takes 1s, so 3 factories make 3 a second
+			name:    "factory rates",
+			opts:    []PlannerOption{WithTargets("Gear:3"), WithFactoryRates(true)},
+			targets: []string{"Gear"},
+			rates:   map[string]float64{"Gear": 3},
+			raw:     map[string]float64{"Iron Ore": 3},
+		},
+		{
+			name:    "target without a rate",
+			opts:    []PlannerOption{WithTargets("Gear", "Circuit Board:2")},
+			targets: []string{"Gear", "Circuit Board"},
+			rates:   map[string]float64{"Circuit Board": 2},
+			raw:     map[string]float64{"Iron Ore": 2, "Copper Ore": 1},
+		},
+		{
+			name:    "have",
+			opts:    []PlannerOption{WithTargets("Gear:1"), WithHave("Iron Ingot")},
+			targets: []string{"Gear"},
+			rates:   map[string]float64{"Gear": 1},
+			raw:     map[string]float64{},
+			have:    map[string]float64{"Iron Ingot": 1},
+			missing: []string{"Iron Ore"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := df.NewPlanner(tt.opts...).Chain()
+			if err != nil {
+				t.Fatalf("Chain() failed: %v", err)
+			}
+			if !reflect.DeepEqual(res.Targets, tt.targets) {
+				t.Errorf("Targets = %v, want %v", res.Targets, tt.targets)
+			}
+			if !reflect.DeepEqual(res.Rates, tt.rates) {
+				t.Errorf("Rates = %v, want %v", res.Rates, tt.rates)
+			}
+			if !reflect.DeepEqual(res.Raw, tt.raw) {
+				t.Errorf("Raw = %v, want %v", res.Raw, tt.raw)
+			}
+			if tt.have != nil && !reflect.DeepEqual(res.Supplied, tt.have) {
+				t.Errorf("Supplied = %v, want %v", res.Supplied, tt.have)
+			}
+			for _, s := range res.Chain.Steps {
+				for _, item := range tt.missing {
+					if s.Target == item {
+						t.Errorf("chain has a step for %s", item)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestPlanner_Plan(t *testing.T) {
+	df := getTestDataFile(t)
+
+	tests := []struct {
+		name string
+		opts []PlannerOption
+		want *Plan
+	}{
+		{
+			name: "rates in the planner's unit",
+			opts: []PlannerOption{WithTargets("Gear:60", "Circuit Board"), WithRateUnit(PerMinute),
+				WithHave("Iron Ingot"), WithExclude("Copper Ore")},
+			want: &Plan{
+				Targets: []PlanTarget{{Item: "Gear", Rate: 1}, {Item: "Circuit Board"}},
+				Have:    []string{"Iron Ingot"},
+				Exclude: []string{"Copper Ore"},
+			},
+		},
+		{
+			// The unit and factory mode apply to targets whatever order the options are given in
+			name: "factory counts",
+			opts: []PlannerOption{WithTarget("Gear", 3), WithRateUnit(PerMinute), WithFactoryRates(true)},
+			want: &Plan{Targets: []PlanTarget{{Item: "Gear", Factories: 3}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := df.NewPlanner(tt.opts...).Plan()
+			if err != nil {
+				t.Fatalf("Plan() failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Plan() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlanner_TargetWithColon(t *testing.T) {
+	df, err := LoadData([]byte(chainTestYAMLData + "\n  - makes: { \"Gear: Large\": 1 }\n    consumes: { Gear: 2 }\n" +
+		"    time: 1\n    facility: [ assembler ]\n"))
+	if err != nil {
+		t.Fatalf("Failed to load test data: %v", err)
+	}
+	res, err := df.NewPlanner(WithTarget("Gear: Large", 1)).Chain()
+	if err != nil {
+		t.Fatalf("Chain() failed: %v", err)
+	}
+	if want := map[string]float64{"Gear: Large": 1}; !reflect.DeepEqual(res.Rates, want) {
+		t.Errorf("Rates = %v, want %v", res.Rates, want)
+	}
+	if want := map[string]float64{"Iron Ore": 2}; !reflect.DeepEqual(res.Raw, want) {
+		t.Errorf("Raw = %v, want %v", res.Raw, want)
+	}
+
+	// As a string, the colon in the name can't be told apart from the one before the rate
+	_, err = df.NewPlanner(WithTargets("Gear: Large:1")).Chain()
+	if err == nil || !strings.Contains(err.Error(), "invalid argument") {
+		t.Errorf("Chain() error = %v, want an invalid argument", err)
+	}
+}
+
+func TestPlanner_Diff(t *testing.T) {
+	df, err := LoadData([]byte(diffTestYAMLData))
+	if err != nil {
+		t.Fatalf("Failed to load test data: %v", err)
+	}
+	oldItems, newItems := []string{"Iron Ore"}, []string{"Copper Ore"}
+	excludeOld, excludeNew := []string{"Gear"}, []string{"Circuit Board"}
+	res, err := df.NewPlanner(WithDiff(oldItems, newItems, excludeOld, excludeNew), WithRateUnit(PerMinute)).Diff()
+	if err != nil {
+		t.Fatalf("Diff() failed: %v", err)
+	}
+	want, err := df.Diff(oldItems, newItems, excludeOld, excludeNew)
+	if err != nil {
+		t.Fatalf("df.Diff() failed: %v", err)
+	}
+	if !reflect.DeepEqual(res.Diff, want) {
+		t.Errorf("Diff = %+v, want %+v", res.Diff, want)
+	}
+	if len(res.Diff.Added) == 0 {
+		t.Errorf("Diff added nothing, want the items made from Copper Ore")
+	}
+	if res.Chain != nil || res.Unit != PerMinute {
+		t.Errorf("Diff() result has Chain %v and Unit %q, want no chain and %q", res.Chain, res.Unit, PerMinute)
+	}
+}
+
+func TestPlanResult_DisplayOptions(t *testing.T) {
+	df := getTestDataFile(t)
+	res, err := df.NewPlanner(WithTargets("Gear:60"), WithRateUnit(PerMinute)).Chain()
+	if err != nil {
+		t.Fatalf("Chain() failed: %v", err)
+	}
+	if res.Unit != PerMinute {
+		t.Errorf("Unit = %q, want %q", res.Unit, PerMinute)
+	}
+	if got, want := res.Chain.Steps[0].StringWithOpts(res.DisplayOptions()...), "Gear (60/min): Iron Ingot"; got != want {
+		t.Errorf("StringWithOpts() = %q, want %q", got, want)
+	}
+	// Later options override the result's unit
+	got := res.Chain.Steps[0].StringWithOpts(res.DisplayOptions(WithDisplayUnit(PerHour))...)
+	if want := "Gear (3600/h): Iron Ingot"; got != want {
+		t.Errorf("StringWithOpts() = %q, want %q", got, want)
+	}
+}
+
+func TestPlanner_Errors(t *testing.T) {
+	df := getTestDataFile(t)
+
+	tests := []struct {
+		name string
+		opts []PlannerOption
+		want string
+	}{
+		{"too many colons", []PlannerOption{WithTargets("Gear:1:2")}, "invalid argument: Gear:1:2"},
+		{"bad rate", []PlannerOption{WithTargets("Gear:fast")}, "invalid rate: fast"},
+		{"factories of unknown item", []PlannerOption{WithTargets("Widget:1"), WithFactoryRates(true)},
+			"unknown target item"},
+		{"unknown item", []PlannerOption{WithTargets("Widget:1")}, "error filling chain"},
+		{"duplicate target", []PlannerOption{WithTargets("Gear:1", "Gear:2")}, "target Gear is given more than once"},
+		{"excluded input", []PlannerOption{WithTargets("Circuit Board:2"), WithExclude("Copper Ingot")},
+			"every process for Circuit Board uses an excluded item"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := df.NewPlanner(tt.opts...).Chain()
+			if err == nil {
+				t.Fatalf("Chain() succeeded, want an error containing %q", tt.want)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Chain() error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
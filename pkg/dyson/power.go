@@ -0,0 +1,23 @@
+package dyson
+
+// Power returns the working power drawn by the chain's buildings, in kW.  Buildings with no power in the data file,
+// like the Mecha, draw nothing, and proliferated steps draw extra power.
+func (pc *ProductionChain) Power() float64 {
+	var total float64
+	for _, step := range pc.Steps {
+		total += pc.StepPower(&step)
+	}
+	return total
+}
+
+// StepPower returns the working power drawn by the buildings for one step of the chain, in kW
+func (pc *ProductionChain) StepPower(step *ProductionStep) float64 {
+	if step.Process == nil || step.Building == "" {
+		return 0
+	}
+	power := step.Factories() * pc.df.Power[step.Building]
+	if pc.proliferated(step.Process) {
+		power *= 1 + proliferatorLevels[pc.proliferator.Level].power
+	}
+	return power
+}
@@ -0,0 +1,47 @@
+package dyson
+
+import (
+	"fmt"
+)
+
+// isRaw reports whether a step gathers a raw resource, such as ore, water, oil or gas, rather than making it from
+// other items
+func (ps *ProductionStep) isRaw() bool {
+	return ps.Process != nil && len(ps.Process.Consumes) == 0
+}
+
+// RawResources returns how fast the chain pulls each raw resource from the planet.  Items you already have are not
+// included, since they aren't part of the chain.
+func (pc *ProductionChain) RawResources() map[string]float64 {
+	raw := make(map[string]float64)
+	for _, step := range pc.Steps {
+		if step.isRaw() && step.Rate > 0 {
+			raw[step.Target] += step.Rate
+		}
+	}
+	return raw
+}
+
+// RawResourcesByTarget works out how much of each raw resource goes into each of the chain's targets, at the rate
+// requested for that target.  It fails if a target's rates can't be solved, such as when it is set after the chain
+// was filled and needs a loop that uses as much as it makes.
+func (pc *ProductionChain) RawResourcesByTarget() (map[string]map[string]float64, error) {
+	byTarget := make(map[string]map[string]float64)
+	for target, rate := range pc.requested {
+		raw := make(map[string]float64)
+		byTarget[target] = raw
+		if rate <= 0 {
+			continue
+		}
+		rates, err := pc.solveRates(map[string]float64{target: rate})
+		if err != nil {
+			return nil, fmt.Errorf("error attributing raw resources to %s: %w", target, err)
+		}
+		for i, step := range pc.Steps {
+			if step.isRaw() && rates[i] > 0 {
+				raw[step.Target] += rates[i]
+			}
+		}
+	}
+	return byTarget, nil
+}
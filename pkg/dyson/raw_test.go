@@ -0,0 +1,116 @@
+package dyson
+
+import (
+	"errors"
+	"maps"
+	"slices"
+	"testing"
+)
+
+func TestProductionChain_RawResources(t *testing.T) {
+	df, err := LoadData([]byte(testYAMLData))
+	if err != nil {
+		t.Fatalf("Failed to load test data: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		rates    map[string]float64
+		have     []string
+		raw      map[string]float64
+		byTarget map[string]map[string]float64
+	}{
+		{
+			name:  "separate targets",
+			rates: map[string]float64{"Gear": 2, "Circuit Board": 2},
+			raw:   map[string]float64{"Iron Ore": 4, "Copper Ore": 1},
+			byTarget: map[string]map[string]float64{
+				"Gear":          {"Iron Ore": 2},
+				"Circuit Board": {"Iron Ore": 2, "Copper Ore": 1},
+			},
+		},
+		{
+			name:  "target used by another target",
+			rates: map[string]float64{"Iron Ingot": 1, "Gear": 1},
+			raw:   map[string]float64{"Iron Ore": 2},
+			byTarget: map[string]map[string]float64{
+				"Iron Ingot": {"Iron Ore": 1},
+				"Gear":       {"Iron Ore": 1},
+			},
+		},
+		{
+			name:  "raw target",
+			rates: map[string]float64{"Copper Ore": 3},
+			raw:   map[string]float64{"Copper Ore": 3},
+			byTarget: map[string]map[string]float64{
+				"Copper Ore": {"Copper Ore": 3},
+			},
+		},
+		{
+			name:  "items you have are not raw resources",
+			rates: map[string]float64{"Circuit Board": 2},
+			have:  []string{"Copper Ingot"},
+			raw:   map[string]float64{"Iron Ore": 2},
+			byTarget: map[string]map[string]float64{
+				"Circuit Board": {"Iron Ore": 2},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ch := df.NewChain(slices.Sorted(maps.Keys(tt.rates)))
+			for item, rate := range tt.rates {
+				err := ch.SetRate(item, rate)
+				if err != nil {
+					t.Fatalf("SetRate() failed: %v", err)
+				}
+			}
+			err := ch.FillChainExcluding(tt.have)
+			if err != nil {
+				t.Fatalf("FillChainExcluding() failed: %v", err)
+			}
+			if got := ch.RawResources(); !maps.Equal(got, tt.raw) {
+				t.Errorf("RawResources() = %v, want %v", got, tt.raw)
+			}
+			got, err := ch.RawResourcesByTarget()
+			if err != nil {
+				t.Fatalf("RawResourcesByTarget() failed: %v", err)
+			}
+			if !maps.EqualFunc(got, tt.byTarget, maps.Equal) {
+				t.Errorf("RawResourcesByTarget() = %v, want %v", got, tt.byTarget)
+			}
+		})
+	}
+}
+
+func TestProductionChain_RawResourcesByTarget_Cycle(t *testing.T) {
+	// Each run makes 2 seeds from 2 seeds and an iron ore, so the loop can never deliver any
+	df, err := LoadData([]byte(chainTestYAMLData + `
+  - makes:
+      Seed: 2
+    consumes:
+      Seed: 2
+      Iron Ore: 1
+    time: 1
+    facility: [ assembler ]
+`))
+	if err != nil {
+		t.Fatalf("Failed to load test data: %v", err)
+	}
+	ch := df.NewChain([]string{"Seed"})
+	err = ch.FillChain()
+	if err != nil {
+		t.Fatalf("FillChain() failed: %v", err)
+	}
+	// With no rate the chain fills, but asking for seeds afterwards can't be solved
+	err = ch.SetRate("Seed", 1)
+	if err != nil {
+		t.Fatalf("SetRate() failed: %v", err)
+	}
+	got, err := ch.RawResourcesByTarget()
+	var cycle *ErrCycle
+	if !errors.As(err, &cycle) || cycle.Item != "Seed" {
+		t.Errorf("RawResourcesByTarget() = %v, %v, want a cycle error for Seed", got, err)
+	}
+}
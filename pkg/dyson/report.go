@@ -0,0 +1,87 @@
+package dyson
+
+import (
+	"html/template"
+	"maps"
+	"slices"
+	"strings"
+)
+
+var reportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+  body { font-family: sans-serif; margin: 2em; color: #222; }
+  table { border-collapse: collapse; margin-bottom: 1.5em; }
+  th, td { border: 1px solid #ccc; padding: 0.3em 0.6em; text-align: left; }
+  th { background: #eef; }
+  tfoot td { font-weight: bold; }
+  .graph { overflow-x: auto; margin-bottom: 1.5em; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<h2>Production graph</h2>
+<div class="graph">
+{{.Graph}}</div>
+<h2>Production steps</h2>
+<table>
+<thead><tr>{{range .Header}}<th>{{.}}</th>{{end}}</tr></thead>
+<tbody>
+{{- range .Rows}}
+<tr>{{range .}}<td>{{.}}</td>{{end}}</tr>
+{{- end}}
+</tbody>
+<tfoot><tr>{{range .Totals}}<td>{{.}}</td>{{end}}</tr></tfoot>
+</table>
+{{- if .Raw}}
+<h2>Raw resources</h2>
+<table>
+<thead><tr><th>Resource</th><th>Rate</th></tr></thead>
+<tbody>
+{{- range .Raw}}
+<tr><td>{{index . 0}}</td><td>{{index . 1}}</td></tr>
+{{- end}}
+</tbody>
+</table>
+{{- end}}
+<h2>Power</h2>
+<p>The buildings draw {{.Power}} kW while working.</p>
+</body>
+</html>
+`))
+
+// HTMLReport formats the chain as a self-contained HTML page, with a graph, a table of the steps, the raw resources
+// used and the power drawn, for sharing a plan with people who don't have the program
+func (pc *ProductionChain) HTMLReport(title string, opts ...StringOption) (string, error) {
+	so := newStringOptions(opts)
+	rows := pc.tableRows(so)
+	raw := pc.RawResources()
+	data := struct {
+		Title  string
+		Graph  template.HTML
+		Header []string
+		Rows   [][]string
+		Totals []string
+		Raw    [][2]string
+		Power  string
+	}{
+		Title:  title,
+		Graph:  template.HTML(pc.SVG(opts...)),
+		Header: rows[0],
+		Rows:   rows[1 : len(rows)-1],
+		Totals: rows[len(rows)-1],
+		Power:  so.number(pc.Power()),
+	}
+	for _, item := range slices.Sorted(maps.Keys(raw)) {
+		data.Raw = append(data.Raw, [2]string{item, so.rate(raw[item])})
+	}
+	sb := strings.Builder{}
+	err := reportTemplate.Execute(&sb, data)
+	if err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,28 @@
+package dyson
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestProductionChain_HTMLReport(t *testing.T) {
+	report, err := newTableTestChain(t).HTMLReport("Boards & <more>")
+	if err != nil {
+		t.Fatalf("HTMLReport() failed: %v", err)
+	}
+	for _, want := range []string{
+		"<title>Boards &amp; &lt;more&gt;</title>",
+		"<svg ",
+		"<td>Circuit Board</td><td>2</td>",
+		"<tfoot><tr><td>Total</td>",
+		"<tr><td>Iron Ore</td><td>2/s</td></tr>",
+		"The buildings draw 2880 kW while working.",
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("HTMLReport() missing %q:\n%s", want, report)
+		}
+	}
+	if strings.Contains(report, "<script") {
+		t.Error("HTMLReport() should not need any scripts")
+	}
+}
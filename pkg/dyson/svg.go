@@ -0,0 +1,188 @@
+package dyson
+
+import (
+	"fmt"
+	"html"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+)
+
+// Sizes used when drawing a chain as SVG, in pixels
+const (
+	svgNodeWidth  = 220
+	svgNodeHeight = 44
+	svgGapX       = 80
+	svgGapY       = 24
+	svgMargin     = 16
+
+	svgMaxNodeHeight = 160
+)
+
+// graphNodes returns every item in the chain, including inputs that the chain doesn't make, in step order, and the
+// edges from each input to the items made from it
+func (pc *ProductionChain) graphNodes() ([]string, [][2]string) {
+	var nodes []string
+	seen := make(map[string]struct{})
+	add := func(item string) {
+		if _, ok := seen[item]; !ok {
+			seen[item] = struct{}{}
+			nodes = append(nodes, item)
+		}
+	}
+	var edges [][2]string
+	for _, step := range pc.Steps {
+		if step.Process == nil {
+			continue
+		}
+		add(step.Target)
+		for _, input := range slices.Sorted(maps.Keys(step.Process.Consumes)) {
+			add(input)
+			edges = append(edges, [2]string{input, step.Target})
+		}
+	}
+	return nodes, edges
+}
+
+// graphColumns places each item in a column one to the right of its furthest input, so raw resources are on the left
+// and the chain's targets are on the right
+func graphColumns(nodes []string, edges [][2]string) map[string]int {
+	inputs := make(map[string][]string)
+	for _, e := range edges {
+		inputs[e[1]] = append(inputs[e[1]], e[0])
+	}
+	columns := make(map[string]int)
+	onPath := make(map[string]struct{})
+	var column func(item string) int
+	column = func(item string) int {
+		if c, ok := columns[item]; ok {
+			return c
+		}
+		if _, ok := onPath[item]; ok {
+			return 0
+		}
+		onPath[item] = struct{}{}
+		c := 0
+		for _, input := range inputs[item] {
+			c = max(c, column(input)+1)
+		}
+		delete(onPath, item)
+		columns[item] = c
+		return c
+	}
+	for _, item := range nodes {
+		column(item)
+	}
+	return columns
+}
+
+// svgNodeSize returns the height of an item's box, which grows with the number of buildings making it
+func svgNodeSize(factories float64) float64 {
+	return math.Round(min(svgNodeHeight+8*math.Sqrt(factories), svgMaxNodeHeight))
+}
+
+// layout places the chain's graph, with each item's inputs to its left
+func (pc *ProductionChain) layout() *graphLayout {
+	nodes, pairs := pc.graphNodes()
+	stepFor := make(map[string]*ProductionStep)
+	for i := range pc.Steps {
+		stepFor[pc.Steps[i].Target] = &pc.Steps[i]
+	}
+	heights := make(map[string]float64)
+	for _, item := range nodes {
+		heights[item] = svgNodeHeight
+		if step, ok := stepFor[item]; ok && step.Building != "" {
+			heights[item] = svgNodeSize(step.Factories())
+		}
+	}
+	var edges []*layoutEdge
+	for _, p := range pairs {
+		step := stepFor[p[1]]
+		var flow float64
+		if itemsPerRun := pc.itemsPerRun(step.Target, step.Process); itemsPerRun > 0 {
+			flow = step.Rate / itemsPerRun * float64(step.Process.Consumes[p[0]])
+		}
+		edges = append(edges, &layoutEdge{from: p[0], to: p[1], flow: flow})
+	}
+	return newGraphLayout(nodes, heights, edges, svgNodeWidth, svgGapX, svgGapY, svgMargin)
+}
+
+// SVG draws the chain as a graph, with each item's inputs to its left.  Boxes are taller for items that need more
+// buildings, and edges are thicker for larger flows.
+func (pc *ProductionChain) SVG(opts ...StringOption) string {
+	so := newStringOptions(opts)
+	gl := pc.layout()
+	stepFor := make(map[string]*ProductionStep)
+	for i := range pc.Steps {
+		stepFor[pc.Steps[i].Target] = &pc.Steps[i]
+	}
+	maxFlow := 0.0
+	for _, e := range gl.edges {
+		maxFlow = max(maxFlow, e.flow)
+	}
+
+	sb := strings.Builder{}
+	sb.WriteString(fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%g" height="%g" viewBox="0 0 %g %g" `+
+		`font-family="sans-serif" font-size="12">`+"\n", gl.width, gl.height, gl.width, gl.height))
+	sb.WriteString(`<defs><marker id="arrow" viewBox="0 0 10 10" refX="10" refY="5" markerWidth="6" markerHeight="6" ` +
+		`markerUnits="userSpaceOnUse" orient="auto"><path d="M0,0 L10,5 L0,10 z" fill="#666"/></marker></defs>` + "\n")
+	for _, e := range gl.edges {
+		width := 1.5
+		if maxFlow > 0 {
+			width = 1 + 5*e.flow/maxFlow
+		}
+		sb.WriteString(fmt.Sprintf(`<path d="%s" fill="none" stroke="#666" stroke-opacity="0.8" stroke-width="%.2f" `+
+			`marker-end="url(#arrow)"><title>%s</title></path>`+"\n", svgEdgePath(e), width,
+			html.EscapeString(fmt.Sprintf("%s to %s: %s", e.from, e.to, so.rate(e.flow)))))
+	}
+	for _, item := range slices.Sorted(maps.Keys(gl.nodes)) {
+		n := gl.nodes[item]
+		fill := "#e8eef8"
+		detail := ""
+		if step, ok := stepFor[item]; ok {
+			if step.Rate > 0 {
+				detail = so.rate(step.Rate)
+				if step.Building != "" {
+					detail += ", " + so.number(step.Factories()) + " " + step.Building
+				}
+			}
+		} else {
+			fill = "#f4f4f4"
+			detail = "supplied"
+		}
+		cx, cy := n.x+svgNodeWidth/2, n.y+n.height/2
+		sb.WriteString(fmt.Sprintf(`<rect x="%g" y="%g" width="%d" height="%g" rx="6" fill="%s" stroke="#456"/>`+"\n",
+			n.x, n.y, svgNodeWidth, n.height, fill))
+		if detail == "" {
+			sb.WriteString(fmt.Sprintf(`<text x="%g" y="%g" text-anchor="middle" font-weight="bold">%s</text>`+"\n",
+				cx, cy+4, html.EscapeString(item)))
+			continue
+		}
+		sb.WriteString(fmt.Sprintf(`<text x="%g" y="%g" text-anchor="middle" font-weight="bold">%s</text>`+"\n",
+			cx, cy-4, html.EscapeString(item)))
+		sb.WriteString(fmt.Sprintf(`<text x="%g" y="%g" text-anchor="middle" fill="#333">%s</text>`+"\n",
+			cx, cy+12, html.EscapeString(detail)))
+	}
+	sb.WriteString("</svg>\n")
+	return sb.String()
+}
+
+// svgEdgePath returns the SVG path of an edge: a curve between each pair of layers, and a straight line through each
+// layer the edge crosses
+func svgEdgePath(e *layoutEdge) string {
+	sb := strings.Builder{}
+	from := e.path[0]
+	x, y := from.x+svgNodeWidth, from.y+from.height/2
+	sb.WriteString(fmt.Sprintf("M%g,%g", x, y))
+	for _, n := range e.path[1:] {
+		ny := n.y + n.height/2
+		sb.WriteString(fmt.Sprintf(" C%g,%g %g,%g %g,%g", x+svgGapX/2, y, n.x-svgGapX/2, ny, n.x, ny))
+		x, y = n.x, ny
+		if n.dummy {
+			x += svgNodeWidth
+			sb.WriteString(fmt.Sprintf(" L%g,%g", x, y))
+		}
+	}
+	return sb.String()
+}
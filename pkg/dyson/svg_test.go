@@ -0,0 +1,51 @@
+package dyson
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func TestProductionChain_SVG(t *testing.T) {
+	pc := newTableTestChain(t)
+
+	nodes, edges := pc.graphNodes()
+	if strings.Join(nodes, ",") != "Circuit Board,Copper Ingot,Iron Ingot,Iron Ore" {
+		t.Errorf("graphNodes() nodes = %v", nodes)
+	}
+	if len(edges) != 3 {
+		t.Errorf("graphNodes() edges = %v, want 3", edges)
+	}
+	columns := graphColumns(nodes, edges)
+	want := map[string]int{"Iron Ore": 0, "Copper Ingot": 0, "Iron Ingot": 1, "Circuit Board": 2}
+	for item, c := range want {
+		if columns[item] != c {
+			t.Errorf("column of %s = %d, want %d", item, columns[item], c)
+		}
+	}
+
+	svg := pc.SVG()
+	if err := xml.Unmarshal([]byte(svg), new(struct{})); err != nil {
+		t.Errorf("SVG() is not valid XML: %v\n%s", err, svg)
+	}
+	for _, s := range []string{"Circuit Board", "2/s, 1 Assembling Machine Mk. II", "supplied"} {
+		if !strings.Contains(svg, s) {
+			t.Errorf("SVG() missing %q:\n%s", s, svg)
+		}
+	}
+	if n := strings.Count(svg, "<rect"); n != 4 {
+		t.Errorf("SVG() has %d nodes, want 4", n)
+	}
+}
+
+func TestSVGNodeSize(t *testing.T) {
+	if svgNodeSize(0) != svgNodeHeight {
+		t.Errorf("svgNodeSize(0) = %v, want %v", svgNodeSize(0), svgNodeHeight)
+	}
+	if !(svgNodeSize(4) < svgNodeSize(16)) {
+		t.Errorf("svgNodeSize(4) = %v should be less than svgNodeSize(16) = %v", svgNodeSize(4), svgNodeSize(16))
+	}
+	if svgNodeSize(10000) != svgMaxNodeHeight {
+		t.Errorf("svgNodeSize(10000) = %v, want %v", svgNodeSize(10000), svgMaxNodeHeight)
+	}
+}
@@ -0,0 +1,60 @@
+package dyson
+
+import (
+	"encoding/csv"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// tableRows returns a header row, one row per step of the chain, and a totals row, for exporting the chain as a table.
+// Rates are in the options' unit.
+func (pc *ProductionChain) tableRows(so *StringOptions) [][]string {
+	rows := [][]string{{"Target", "Rate (" + so.unit.Suffix() + ")", "Recipe", "Inputs", "Facility", "Building",
+		"Factories", "Power (kW)"}}
+	var factories, power float64
+	for _, step := range pc.Steps {
+		row := []string{step.Target, so.number(so.unit.FromPerSecond(step.Rate)), "", "", step.Facility,
+			step.Building, "", ""}
+		if step.Process != nil {
+			row[2] = step.Process.Recipe()
+			row[3] = strings.Join(slices.Sorted(maps.Keys(step.Process.Consumes)), ", ")
+		}
+		if step.Building != "" && step.Rate > 0 {
+			row[6] = so.number(step.Factories())
+			row[7] = so.number(pc.StepPower(&step))
+			factories += step.Factories()
+			power += pc.StepPower(&step)
+		}
+		rows = append(rows, row)
+	}
+	rows = append(rows, []string{"Total", "", "", "", "", "", so.number(factories), so.number(power)})
+	return rows
+}
+
+// CSV formats the chain as comma-separated values, with a header row, one row per step and a totals row
+func (pc *ProductionChain) CSV(opts ...StringOption) string {
+	sb := strings.Builder{}
+	w := csv.NewWriter(&sb)
+	_ = w.WriteAll(pc.tableRows(newStringOptions(opts)))
+	return sb.String()
+}
+
+// MarkdownTable formats the chain as a Markdown table, with one row per step and a totals row
+func (pc *ProductionChain) MarkdownTable(opts ...StringOption) string {
+	rows := pc.tableRows(newStringOptions(opts))
+	sb := strings.Builder{}
+	writeRow := func(cells []string) {
+		sb.WriteString("|")
+		for _, c := range cells {
+			sb.WriteString(" " + strings.ReplaceAll(c, "|", `\|`) + " |")
+		}
+		sb.WriteString("\n")
+	}
+	writeRow(rows[0])
+	sb.WriteString(strings.Repeat("| --- ", len(rows[0])) + "|\n")
+	for _, row := range rows[1:] {
+		writeRow(row)
+	}
+	return sb.String()
+}
@@ -0,0 +1,49 @@
+package dyson
+
+import (
+	"testing"
+)
+
+func newTableTestChain(t *testing.T) *ProductionChain {
+	df, err := LoadData([]byte(powerTestYAMLData))
+	if err != nil {
+		t.Fatalf("Failed to load test data: %v", err)
+	}
+	pc := df.NewChain([]string{"Circuit Board"})
+	err = pc.SetRate("Circuit Board", 2)
+	if err != nil {
+		t.Fatalf("SetRate() failed: %v", err)
+	}
+	err = pc.FillChainExcluding([]string{"Copper Ingot"})
+	if err != nil {
+		t.Fatalf("FillChainExcluding() failed: %v", err)
+	}
+	return pc
+}
+
+func TestProductionChain_CSV(t *testing.T) {
+	want := `Target,Rate (/s),Recipe,Inputs,Facility,Building,Factories,Power (kW)
+Circuit Board,2,"2 Circuit Board from 1 Copper Ingot, 2 Iron Ingot in 1s","Copper Ingot, Iron Ingot",assembler,Assembling Machine Mk. II,1,480
+Iron Ingot,2,1 Iron Ingot from 1 Iron Ore in 1s,Iron Ore,smelter,Arc Smelter,2,720
+Iron Ore,2,1 Iron Ore in 2s,,mine,Mining Machine,4,1680
+Total,,,,,,7,2880
+`
+	if got := newTableTestChain(t).CSV(); got != want {
+		t.Errorf("CSV() =\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestProductionChain_MarkdownTable(t *testing.T) {
+	pc := newTableTestChain(t)
+	pc.Steps[0].Building = "Assembler | Mk. II"
+	want := `| Target | Rate (/s) | Recipe | Inputs | Facility | Building | Factories | Power (kW) |
+| --- | --- | --- | --- | --- | --- | --- | --- |
+| Circuit Board | 2 | 2 Circuit Board from 1 Copper Ingot, 2 Iron Ingot in 1s | Copper Ingot, Iron Ingot | assembler | Assembler \| Mk. II | 1 | 0 |
+| Iron Ingot | 2 | 1 Iron Ingot from 1 Iron Ore in 1s | Iron Ore | smelter | Arc Smelter | 2 | 720 |
+| Iron Ore | 2 | 1 Iron Ore in 2s |  | mine | Mining Machine | 4 | 1680 |
+| Total |  |  |  |  |  | 7 | 2400 |
+`
+	if got := pc.MarkdownTable(); got != want {
+		t.Errorf("MarkdownTable() =\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -0,0 +1,118 @@
+package dyson
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Technology is one research in the tech tree
+type Technology struct {
+	Requires []string       `yaml:"requires,flow,omitempty"` // technologies that must be researched first
+	Cost     map[string]int `yaml:"cost,omitempty"`          // items consumed by the research, usually matrices
+
+	pos Position // where the technology was defined
+}
+
+// ProcessTechs returns the technologies needed to use a process: its own tech if it names one, or else the tech that
+// unlocks each item it makes
+func (df *DataFile) ProcessTechs(proc *Process) []string {
+	if proc.Tech != "" {
+		return []string{proc.Tech}
+	}
+	var techs []string
+	for _, m := range slices.Sorted(maps.Keys(proc.Makes)) {
+		tech := df.Items[m].Tech
+		if tech != "" && !slices.Contains(techs, tech) {
+			techs = append(techs, tech)
+		}
+	}
+	return techs
+}
+
+// TechPath returns the given technologies and everything they require, in an order they can be researched in
+func (df *DataFile) TechPath(techs ...string) ([]string, error) {
+	var path []string
+	done := make(map[string]struct{})
+	visiting := make(map[string]struct{})
+	var visit func(tech string) error
+	visit = func(tech string) error {
+		if _, ok := done[tech]; ok {
+			return nil
+		}
+		if _, ok := visiting[tech]; ok {
+			return fmt.Errorf("technology %s requires itself", tech)
+		}
+		t, ok := df.Technologies[tech]
+		if !ok {
+			return fmt.Errorf("unknown technology: %s", tech)
+		}
+		visiting[tech] = struct{}{}
+		for _, req := range t.Requires {
+			err := visit(req)
+			if err != nil {
+				return err
+			}
+		}
+		delete(visiting, tech)
+		done[tech] = struct{}{}
+		path = append(path, tech)
+		return nil
+	}
+	for _, tech := range techs {
+		err := visit(tech)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return path, nil
+}
+
+// TechCost returns the total cost of researching the given technologies
+func (df *DataFile) TechCost(techs []string) map[string]int {
+	cost := make(map[string]int)
+	for _, tech := range techs {
+		for item, count := range df.Technologies[tech].Cost {
+			cost[item] += count
+		}
+	}
+	return cost
+}
+
+// WithResearched returns a copy of the data file that only has the processes unlocked by the given technologies.
+// Prerequisites are not added, so pass the result of TechPath to include them.
+func (df *DataFile) WithResearched(researched []string) (*DataFile, error) {
+	have := make(map[string]struct{})
+	for _, tech := range researched {
+		if _, ok := df.Technologies[tech]; !ok {
+			return nil, fmt.Errorf("unknown technology: %s", tech)
+		}
+		have[tech] = struct{}{}
+	}
+	restricted := *df
+	restricted.Processes = nil
+	for _, proc := range df.Processes {
+		unlocked := true
+		for _, tech := range df.ProcessTechs(&proc) {
+			if _, ok := have[tech]; !ok {
+				unlocked = false
+				break
+			}
+		}
+		if unlocked {
+			restricted.Processes = append(restricted.Processes, proc)
+		}
+	}
+	restricted.index()
+	return &restricted, nil
+}
+
+// describeTech summarises a technology, for conflict messages
+func describeTech(t Technology) string {
+	var costs []string
+	for _, item := range slices.Sorted(maps.Keys(t.Cost)) {
+		costs = append(costs, fmt.Sprintf("%d %s", t.Cost[item], item))
+	}
+	return fmt.Sprintf("requires [%s], costs [%s]", strings.Join(t.Requires, ", "), strings.Join(costs, ", "))
+}
@@ -0,0 +1,150 @@
+package dyson
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+var techTestYAMLData = `
+facilities:
+  mine:
+    Mining Machine: 1
+  smelter:
+    Arc Smelter: 1
+  assembler:
+    Assembling Machine Mk. I: 1
+items:
+  Iron Ingot: { category: component, stack: 100 }
+  Gear: { category: component, stack: 200, tech: Gears }
+  Matrix: { category: matrix, stack: 200, tech: Matrices }
+technologies:
+  Basics: { cost: { Iron Ingot: 10 } }
+  Matrices: { requires: [ Basics ], cost: { Gear: 10 } }
+  Gears: { requires: [ Basics ], cost: { Iron Ingot: 20 } }
+  Cheap Gears: { requires: [ Gears, Matrices ], cost: { Matrix: 50 } }
+processes:
+  - makes:
+      Iron Ore: 1
+    time: 2
+    facility: [ mine ]
+  - makes:
+      Iron Ingot: 1
+    consumes:
+      Iron Ore: 1
+    time: 1
+    facility: [ smelter ]
+  - makes:
+      Gear: 1
+    consumes:
+      Iron Ingot: 1
+    time: 1
+    facility: [ assembler ]
+  - makes:
+      Gear: 2
+    consumes:
+      Iron Ore: 1
+    time: 1
+    facility: [ assembler ]
+    tech: Cheap Gears
+    special: true
+  - makes:
+      Matrix: 1
+    consumes:
+      Gear: 1
+    time: 3
+    facility: [ assembler ]
+`
+
+func TestDataFile_TechPath(t *testing.T) {
+	df, err := LoadData([]byte(techTestYAMLData))
+	if err != nil {
+		t.Fatalf("Failed to load test data: %v", err)
+	}
+	err = df.Validate()
+	if err != nil {
+		t.Fatalf("Validate() failed: %v", err)
+	}
+
+	path, err := df.TechPath("Cheap Gears")
+	if err != nil {
+		t.Fatalf("TechPath() failed: %v", err)
+	}
+	want := []string{"Basics", "Gears", "Matrices", "Cheap Gears"}
+	if !slices.Equal(path, want) {
+		t.Errorf("TechPath() = %v, want %v", path, want)
+	}
+
+	cost := df.TechCost(path)
+	if cost["Iron Ingot"] != 30 || cost["Gear"] != 10 || cost["Matrix"] != 50 || len(cost) != 3 {
+		t.Errorf("TechCost() = %v", cost)
+	}
+
+	_, err = df.TechPath("Time Travel")
+	if err == nil || !strings.Contains(err.Error(), "unknown technology: Time Travel") {
+		t.Errorf("TechPath() error = %v, want unknown technology", err)
+	}
+}
+
+func TestDataFile_WithResearched(t *testing.T) {
+	df, err := LoadData([]byte(techTestYAMLData))
+	if err != nil {
+		t.Fatalf("Failed to load test data: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		researched []string
+		gearProcs  int
+		matrix     bool
+	}{
+		{name: "nothing researched", researched: []string{}, gearProcs: 0, matrix: false},
+		{name: "gears", researched: []string{"Basics", "Gears"}, gearProcs: 1, matrix: false},
+		{name: "everything", researched: []string{"Basics", "Gears", "Matrices", "Cheap Gears"}, gearProcs: 2,
+			matrix: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			restricted, err := df.WithResearched(tt.researched)
+			if err != nil {
+				t.Fatalf("WithResearched() failed: %v", err)
+			}
+			if len(restricted.procsByTarget["Gear"]) != tt.gearProcs {
+				t.Errorf("Gear processes = %d, want %d", len(restricted.procsByTarget["Gear"]), tt.gearProcs)
+			}
+			if restricted.Makeable("Matrix") != tt.matrix {
+				t.Errorf("Makeable(Matrix) = %v, want %v", restricted.Makeable("Matrix"), tt.matrix)
+			}
+			if !restricted.Makeable("Iron Ingot") {
+				t.Error("items without a technology should always be makeable")
+			}
+		})
+	}
+	if len(df.procsByTarget["Gear"]) != 2 {
+		t.Error("WithResearched() should not modify the original data file")
+	}
+
+	_, err = df.WithResearched([]string{"Time Travel"})
+	if err == nil || !strings.Contains(err.Error(), "unknown technology: Time Travel") {
+		t.Errorf("WithResearched() error = %v, want unknown technology", err)
+	}
+}
+
+func TestDataFile_ProcessTechs(t *testing.T) {
+	df, err := LoadData([]byte(techTestYAMLData))
+	if err != nil {
+		t.Fatalf("Failed to load test data: %v", err)
+	}
+	for _, proc := range df.procsByTarget["Gear"] {
+		want := "Gears"
+		if proc.Special {
+			want = "Cheap Gears"
+		}
+		if got := df.ProcessTechs(&proc); !slices.Equal(got, []string{want}) {
+			t.Errorf("ProcessTechs() = %v, want [%s]", got, want)
+		}
+	}
+	if got := df.ProcessTechs(&df.procsByTarget["Iron Ingot"][0]); len(got) != 0 {
+		t.Errorf("ProcessTechs(Iron Ingot) = %v, want none", got)
+	}
+}
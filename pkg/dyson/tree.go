@@ -0,0 +1,78 @@
+package dyson
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// WithCollapsedSubchains shows a sub-chain that already appeared in a tree as a reference, instead of repeating it
+func WithCollapsedSubchains() func(options *StringOptions) {
+	return func(options *StringOptions) {
+		options.collapse = true
+	}
+}
+
+// TreeString formats a filled chain as a dependency tree, from each of its targets down to raw resources.  Rates and
+// building counts are for each branch, so an item used in several places shows how much of it goes where.
+func (pc *ProductionChain) TreeString(opts ...StringOption) string {
+	so := newStringOptions(opts)
+	stepFor := make(map[string]*ProductionStep)
+	for i := range pc.Steps {
+		stepFor[pc.Steps[i].Target] = &pc.Steps[i]
+	}
+
+	sb := strings.Builder{}
+	expanded := make(map[string]struct{})
+	onPath := make(map[string]struct{})
+	var write func(item string, rate float64, prefix string, childPrefix string)
+	write = func(item string, rate float64, prefix string, childPrefix string) {
+		sb.WriteString(prefix + item + so.rateString(item, rate))
+		step, ok := stepFor[item]
+		if !ok || step.Process == nil {
+			sb.WriteString(" (have)\n")
+			return
+		}
+		if so.showBuildings && step.Building != "" && rate > 0 && step.Rate > 0 {
+			sb.WriteString(fmt.Sprintf(" [%s %s]", so.number(step.Factories()*rate/step.Rate), step.Building))
+		}
+		inputs := slices.Sorted(maps.Keys(step.Process.Consumes))
+		if len(inputs) == 0 {
+			sb.WriteString("\n")
+			return
+		}
+		if _, ok := onPath[item]; ok {
+			sb.WriteString(" (cycle)\n")
+			return
+		}
+		if _, ok := expanded[item]; ok && so.collapse {
+			sb.WriteString(" (see above)\n")
+			return
+		}
+		sb.WriteString("\n")
+		expanded[item] = struct{}{}
+		onPath[item] = struct{}{}
+		defer delete(onPath, item)
+
+		var runsPerSecond float64
+		if itemsPerRun := pc.itemsPerRun(item, step.Process); itemsPerRun > 0 {
+			runsPerSecond = rate / itemsPerRun
+		}
+		for i, input := range inputs {
+			inputRate := runsPerSecond * float64(step.Process.Consumes[input])
+			if i == len(inputs)-1 {
+				write(input, inputRate, childPrefix+"└─ ", childPrefix+"   ")
+			} else {
+				write(input, inputRate, childPrefix+"├─ ", childPrefix+"│  ")
+			}
+		}
+	}
+
+	for _, step := range pc.Steps {
+		if rate, ok := pc.requested[step.Target]; ok {
+			write(step.Target, rate, "", "")
+		}
+	}
+	return sb.String()
+}
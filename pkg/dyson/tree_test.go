@@ -0,0 +1,76 @@
+package dyson
+
+import (
+	"testing"
+)
+
+func TestProductionChain_TreeString(t *testing.T) {
+	df, err := LoadData([]byte(testYAMLData))
+	if err != nil {
+		t.Fatalf("Failed to load test data: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		have []string
+		opts []StringOption
+		want string
+	}{
+		{
+			name: "full tree",
+			want: `Circuit Board (2/s)
+├─ Copper Ingot (1/s)
+│  └─ Copper Ore (1/s)
+└─ Iron Ingot (2/s)
+   └─ Iron Ore (2/s)
+Gear (1/s)
+└─ Iron Ingot (1/s)
+   └─ Iron Ore (1/s)
+`,
+		},
+		{
+			name: "buildings per branch",
+			have: []string{"Copper Ingot"},
+			opts: []StringOption{WithBuildings()},
+			want: `Circuit Board (2/s) [1 Assembling Machine Mk. II]
+├─ Copper Ingot (1/s) (have)
+└─ Iron Ingot (2/s) [2 Arc Smelter]
+   └─ Iron Ore (2/s) [4 Mining Machine]
+Gear (1/s) [1 Assembling Machine Mk. II]
+└─ Iron Ingot (1/s) [1 Arc Smelter]
+   └─ Iron Ore (1/s) [2 Mining Machine]
+`,
+		},
+		{
+			name: "collapsed sub-chains",
+			opts: []StringOption{WithCollapsedSubchains()},
+			want: `Circuit Board (2/s)
+├─ Copper Ingot (1/s)
+│  └─ Copper Ore (1/s)
+└─ Iron Ingot (2/s)
+   └─ Iron Ore (2/s)
+Gear (1/s)
+└─ Iron Ingot (1/s) (see above)
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pc := df.NewChain([]string{"Circuit Board", "Gear"})
+			for item, rate := range map[string]float64{"Circuit Board": 2, "Gear": 1} {
+				err := pc.SetRate(item, rate)
+				if err != nil {
+					t.Fatalf("SetRate() failed: %v", err)
+				}
+			}
+			err := pc.FillChainExcluding(tt.have)
+			if err != nil {
+				t.Fatalf("FillChainExcluding() failed: %v", err)
+			}
+			if got := pc.TreeString(tt.opts...); got != tt.want {
+				t.Errorf("TreeString() =\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package dyson
+
+import (
+	"fmt"
+)
+
+// RateUnit is the time unit that rates are given and shown in.  Rates are always calculated in items per second.
+type RateUnit string
+
+const (
+	PerSecond RateUnit = "s"
+	PerMinute RateUnit = "min"
+	PerHour   RateUnit = "h"
+)
+
+// rateUnitSeconds is the length of each unit in seconds
+var rateUnitSeconds = map[RateUnit]float64{
+	PerSecond: 1,
+	PerMinute: 60,
+	PerHour:   3600,
+}
+
+// ParseRateUnit converts a unit name (s, min or h) to a RateUnit
+func ParseRateUnit(s string) (RateUnit, error) {
+	u := RateUnit(s)
+	if _, ok := rateUnitSeconds[u]; !ok {
+		return "", fmt.Errorf("unknown rate unit: %s (expected s, min or h)", s)
+	}
+	return u, nil
+}
+
+// ToPerSecond converts a rate in this unit to items per second
+func (u RateUnit) ToPerSecond(rate float64) float64 {
+	return rate / rateUnitSeconds[u]
+}
+
+// FromPerSecond converts a rate in items per second to this unit
+func (u RateUnit) FromPerSecond(rate float64) float64 {
+	return rate * rateUnitSeconds[u]
+}
+
+// Suffix returns the suffix written after rates in this unit, such as /min
+func (u RateUnit) Suffix() string {
+	return "/" + string(u)
+}
+
+// WithDisplayUnit shows rates in the given unit instead of items per second
+func WithDisplayUnit(unit RateUnit) func(options *StringOptions) {
+	return func(options *StringOptions) {
+		options.unit = unit
+	}
+}
+
+// FormatRate formats a rate given in items per second, with its unit suffix.  It shows items per second to
+// DefaultPrecision decimal places unless WithDisplayUnit or WithPrecision is given.
+func FormatRate(rate float64, opts ...StringOption) string {
+	return newStringOptions(opts).rate(rate)
+}
+
+// rate formats a rate given in items per second in the options' unit, with its suffix
+func (so *StringOptions) rate(rate float64) string {
+	return so.number(so.unit.FromPerSecond(rate)) + so.unit.Suffix()
+}
@@ -0,0 +1,69 @@
+package dyson
+
+import (
+	"testing"
+)
+
+func TestParseRateUnit(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    RateUnit
+		wantErr bool
+	}{
+		{name: "s", want: PerSecond},
+		{name: "min", want: PerMinute},
+		{name: "h", want: PerHour},
+		{name: "day", wantErr: true},
+		{name: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseRateUnit(tt.name)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseRateUnit(%q) = %q, %v, want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestRateUnit_Convert(t *testing.T) {
+	tests := []struct {
+		unit      RateUnit
+		perSecond float64
+		inUnit    float64
+		formatted string
+	}{
+		{unit: PerSecond, perSecond: 1.5, inUnit: 1.5, formatted: "1.5/s"},
+		{unit: PerMinute, perSecond: 1.5, inUnit: 90, formatted: "90/min"},
+		{unit: PerHour, perSecond: 0.5, inUnit: 1800, formatted: "1800/h"},
+	}
+	for _, tt := range tests {
+		if got := tt.unit.FromPerSecond(tt.perSecond); got != tt.inUnit {
+			t.Errorf("%s FromPerSecond(%v) = %v, want %v", tt.unit, tt.perSecond, got, tt.inUnit)
+		}
+		if got := tt.unit.ToPerSecond(tt.inUnit); got != tt.perSecond {
+			t.Errorf("%s ToPerSecond(%v) = %v, want %v", tt.unit, tt.inUnit, got, tt.perSecond)
+		}
+		if got := FormatRate(tt.perSecond, WithDisplayUnit(tt.unit)); got != tt.formatted {
+			t.Errorf("%s FormatRate(%v) = %q, want %q", tt.unit, tt.perSecond, got, tt.formatted)
+		}
+	}
+}
+
+func TestProductionStep_StringInDisplayUnit(t *testing.T) {
+	df := getTestDataFile(t)
+	pc := df.NewChain([]string{"Gear"})
+	err := pc.SetRate("Gear", 0.5)
+	if err != nil {
+		t.Fatalf("SetRate() failed: %v", err)
+	}
+	err = pc.FillChain()
+	if err != nil {
+		t.Fatalf("FillChain() failed: %v", err)
+	}
+
+	if got, want := pc.Steps[0].StringWithOpts(WithDisplayUnit(PerMinute)), "Gear (30/min): Iron Ingot"; got != want {
+		t.Errorf("StringWithOpts() = %q, want %q", got, want)
+	}
+	if got, want := pc.Steps[0].String(), "Gear (0.5/s): Iron Ingot"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,49 @@
+package dyson
+
+import (
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Use is a process that consumes an item, as found by Uses
+type Use struct {
+	Item    string   // the item consumed
+	Process *Process // the process consuming it
+	Depth   int      // 1 for processes that consume the original item directly
+}
+
+// Consumers returns every process that consumes an item
+func (df *DataFile) Consumers(item string) []Process {
+	return df.procsByInput[item]
+}
+
+// Uses finds the processes that consume an item, and then the processes that consume what those make, down to the
+// given depth.  A depth of zero or less has no limit.  The result is in depth-first order, so each use is followed by
+// the uses of what it makes, and the uses of each item are only listed the first time it is reached.
+func (df *DataFile) Uses(item string, depth int) []Use {
+	var uses []Use
+	expanded := map[string]struct{}{item: {}}
+	var visit func(item string, d int)
+	visit = func(item string, d int) {
+		procs := slices.Clone(df.procsByInput[item])
+		slices.SortStableFunc(procs, func(a, b Process) int {
+			return strings.Compare(describeProcess(a.Makes, a.Consumes), describeProcess(b.Makes, b.Consumes))
+		})
+		for i := range procs {
+			uses = append(uses, Use{Item: item, Process: &procs[i], Depth: d})
+			if depth > 0 && d >= depth {
+				continue
+			}
+			for _, m := range slices.Sorted(maps.Keys(procs[i].Makes)) {
+				if _, ok := expanded[m]; ok {
+					continue
+				}
+				expanded[m] = struct{}{}
+				visit(m, d+1)
+			}
+		}
+	}
+	visit(item, 1)
+	return uses
+}
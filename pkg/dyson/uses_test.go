@@ -0,0 +1,89 @@
+package dyson
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDataFile_Consumers(t *testing.T) {
+	df, err := LoadData([]byte(mallTestYAMLData))
+	if err != nil {
+		t.Fatalf("Failed to load test data: %v", err)
+	}
+	var got []string
+	for _, proc := range df.Consumers("Gear") {
+		got = append(got, slices.Sorted(maps.Keys(proc.Makes))...)
+	}
+	slices.Sort(got)
+	if !slices.Equal(got, []string{"Belt", "Fast Sorter"}) {
+		t.Errorf("Consumers(Gear) make %v, want [Belt Fast Sorter]", got)
+	}
+	if len(df.Consumers("Fast Sorter")) != 0 {
+		t.Error("nothing should consume Fast Sorter")
+	}
+}
+
+func TestDataFile_Uses(t *testing.T) {
+	df, err := LoadData([]byte(mallTestYAMLData))
+	if err != nil {
+		t.Fatalf("Failed to load test data: %v", err)
+	}
+	tests := []struct {
+		name  string
+		item  string
+		depth int
+		want  []string
+	}{
+		{
+			name:  "direct uses",
+			item:  "Copper Ingot",
+			depth: 1,
+			want:  []string{"1 Copper Ingot -> Circuit Board"},
+		},
+		{
+			name:  "transitive uses",
+			item:  "Copper Ingot",
+			depth: 0,
+			want: []string{
+				"1 Copper Ingot -> Circuit Board",
+				"2 Circuit Board -> Sorter",
+				"3 Sorter -> Fast Sorter",
+			},
+		},
+		{
+			name:  "limited depth",
+			item:  "Iron Ingot",
+			depth: 2,
+			want: []string{
+				"1 Iron Ingot -> Belt",
+				"1 Iron Ingot -> Circuit Board",
+				"2 Circuit Board -> Sorter",
+				"1 Iron Ingot -> Depot",
+				"1 Iron Ingot -> Gear",
+				"2 Gear -> Belt",
+				"2 Gear -> Fast Sorter",
+				"1 Iron Ingot -> Sorter",
+				"2 Sorter -> Fast Sorter",
+			},
+		},
+		{
+			name: "unused item",
+			item: "Depot",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, u := range df.Uses(tt.item, tt.depth) {
+				makes := strings.Join(slices.Sorted(maps.Keys(u.Process.Makes)), ", ")
+				got = append(got, fmt.Sprintf("%d %s -> %s", u.Depth, u.Item, makes))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Uses() =\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
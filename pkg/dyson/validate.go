@@ -0,0 +1,337 @@
+package dyson
+
+import (
+	"cmp"
+	"fmt"
+	yaml "gopkg.in/yaml.v3"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Position is a location in a data file.  File is empty for data that was not loaded from a named file, and Line
+// is zero if the location is unknown.
+type Position struct {
+	File   string
+	Line   int
+	Column int
+}
+
+func (p Position) String() string {
+	if p.Line == 0 {
+		return p.File
+	}
+	if p.File == "" {
+		return fmt.Sprintf("%d:%d", p.Line, p.Column)
+	}
+	return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Column)
+}
+
+// Problem is an issue found when validating a data file
+type Problem struct {
+	Pos     Position
+	Message string
+}
+
+func (p Problem) String() string {
+	pos := p.Pos.String()
+	if pos == "" {
+		return p.Message
+	}
+	return pos + ": " + p.Message
+}
+
+// ValidationError reports every problem found in a data file
+type ValidationError struct {
+	Problems []Problem
+}
+
+func (e *ValidationError) Error() string {
+	var lines []string
+	for _, p := range e.Problems {
+		lines = append(lines, p.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Validate checks the data file for problems, returning a *ValidationError listing all of them
+func (df *DataFile) Validate() error {
+	problems := df.Lint()
+	if len(problems) == 0 {
+		return nil
+	}
+	return &ValidationError{Problems: problems}
+}
+
+// Lint checks the data file for problems and returns all of them, sorted by position
+func (df *DataFile) Lint() []Problem {
+	var problems []Problem
+	report := func(pos Position, format string, args ...any) {
+		problems = append(problems, Problem{Pos: pos, Message: fmt.Sprintf(format, args...)})
+	}
+
+	// Check facility speeds, and that no building belongs to more than one facility type
+	facTypeOf := make(map[string]string)
+	for _, facType := range slices.Sorted(maps.Keys(df.Facilities)) {
+		for _, fac := range slices.Sorted(maps.Keys(df.Facilities[facType])) {
+			pos := df.facilityPos[facType+"\x00"+fac]
+			if other, ok := facTypeOf[fac]; ok {
+				report(pos, "duplicate facility: %s is in both %s and %s", fac, other, facType)
+			}
+			facTypeOf[fac] = facType
+			rate := df.Facilities[facType][fac]
+			if rate == 0 {
+				report(pos, "facility rate is zero: %s", fac)
+			} else if rate < 0 {
+				report(pos, "facility rate is negative: %s", fac)
+			}
+		}
+	}
+
+	for _, building := range slices.Sorted(maps.Keys(df.Power)) {
+		pos := df.powerPos[building]
+		if _, ok := facTypeOf[building]; !ok {
+			report(pos, "power given for unknown building: %s", building)
+		}
+		if df.Power[building] < 0 {
+			report(pos, "power is negative: %s", building)
+		}
+	}
+
+	seen := make(map[string]Position)
+	seenIDs := make(map[int]Position)
+	for _, proc := range df.Processes {
+		desc := describeProcess(proc.Makes, proc.Consumes)
+		if len(proc.Makes) == 0 {
+			report(proc.pos, "process makes nothing")
+		}
+		for _, item := range slices.Sorted(maps.Keys(proc.Makes)) {
+			if proc.Makes[item] <= 0 {
+				report(proc.pos, "%s: count of %s is not positive", desc, item)
+			}
+		}
+		for _, item := range slices.Sorted(maps.Keys(proc.Consumes)) {
+			if proc.Consumes[item] <= 0 {
+				report(proc.pos, "%s: count of %s is not positive", desc, item)
+			}
+		}
+		if proc.Time <= 0 {
+			report(proc.pos, "%s: time is not positive", desc)
+		}
+		if len(proc.Facility) == 0 {
+			report(proc.pos, "%s: no facility given", desc)
+		}
+		for _, facType := range proc.Facility {
+			if _, ok := df.Facilities[facType]; !ok {
+				report(proc.pos, "%s: unknown facility type: %s", desc, facType)
+			}
+		}
+		if _, ok := df.Technologies[proc.Tech]; proc.Tech != "" && !ok {
+			report(proc.pos, "%s: unknown technology: %s", desc, proc.Tech)
+		}
+
+		if proc.ID < 0 {
+			report(proc.pos, "%s: recipe ID is negative", desc)
+		} else if first, ok := seenIDs[proc.ID]; ok {
+			report(proc.pos, "%s: recipe ID %d is also used by the process at %s", desc, proc.ID, first)
+		} else if proc.ID > 0 {
+			seenIDs[proc.ID] = proc.pos
+		}
+
+		key := processKey(&proc)
+		if first, ok := seen[key]; ok {
+			report(proc.pos, "%s: duplicate of process at %s", desc, first)
+		} else {
+			seen[key] = proc.pos
+		}
+
+		if proc.Special {
+			for _, item := range slices.Sorted(maps.Keys(proc.Makes)) {
+				if !slices.ContainsFunc(df.procsByTarget[item], func(p Process) bool { return !p.Special }) {
+					report(proc.pos, "%s: special process is the only way to make %s", desc, item)
+				}
+			}
+		}
+	}
+
+	// Make sure every mentioned item is either a resource or makeable
+	users := make(map[string]*Process)
+	for i, proc := range df.Processes {
+		for m := range proc.Makes {
+			if users[m] == nil {
+				users[m] = &df.Processes[i]
+			}
+		}
+		for c := range proc.Consumes {
+			if users[c] == nil {
+				users[c] = &df.Processes[i]
+			}
+		}
+	}
+	for _, item := range slices.Sorted(maps.Keys(users)) {
+		if !df.Makeable(item) {
+			proc := users[item]
+			report(proc.pos, "item cannot be made: %s (used by %s)", item, describeProcess(proc.Makes, proc.Consumes))
+		}
+	}
+
+	// Check item metadata, and that it describes items the processes actually use
+	for _, name := range slices.Sorted(maps.Keys(df.Items)) {
+		item := df.Items[name]
+		if !slices.Contains(ItemCategories, item.Category) {
+			report(item.pos, "item %s: unknown category: %s", name, item.Category)
+		}
+		if item.Stack < 0 {
+			report(item.pos, "item %s: stack size is negative", name)
+		}
+		if item.Fuel < 0 {
+			report(item.pos, "item %s: fuel value is negative", name)
+		}
+		if item.Category == CategoryFuel && item.Fuel == 0 {
+			report(item.pos, "item %s: fuel item has no fuel value", name)
+		}
+		if users[name] == nil {
+			report(item.pos, "item %s is not made or used by any process", name)
+		}
+		if _, ok := df.Technologies[item.Tech]; item.Tech != "" && !ok {
+			report(item.pos, "item %s: unknown technology: %s", name, item.Tech)
+		}
+	}
+
+	// Check that the tech tree only refers to known technologies and items, and has no cycles
+	for _, name := range slices.Sorted(maps.Keys(df.Technologies)) {
+		tech := df.Technologies[name]
+		for _, req := range tech.Requires {
+			if _, ok := df.Technologies[req]; !ok {
+				report(tech.pos, "technology %s: unknown prerequisite: %s", name, req)
+			}
+		}
+		for _, item := range slices.Sorted(maps.Keys(tech.Cost)) {
+			if users[item] == nil {
+				report(tech.pos, "technology %s: cost uses unknown item: %s", name, item)
+			}
+			if tech.Cost[item] <= 0 {
+				report(tech.pos, "technology %s: cost of %s is not positive", name, item)
+			}
+		}
+		if df.techRequires(name, name, make(map[string]struct{})) {
+			report(tech.pos, "technology %s requires itself", name)
+		}
+	}
+
+	slices.SortStableFunc(problems, func(a, b Problem) int {
+		return cmp.Or(
+			cmp.Compare(a.Pos.File, b.Pos.File),
+			cmp.Compare(a.Pos.Line, b.Pos.Line),
+			cmp.Compare(a.Pos.Column, b.Pos.Column),
+		)
+	})
+	return problems
+}
+
+// techRequires reports whether a technology depends on another, directly or through its prerequisites
+func (df *DataFile) techRequires(tech string, target string, seen map[string]struct{}) bool {
+	for _, req := range df.Technologies[tech].Requires {
+		if req == target {
+			return true
+		}
+		if _, ok := seen[req]; ok {
+			continue
+		}
+		seen[req] = struct{}{}
+		if df.techRequires(req, target, seen) {
+			return true
+		}
+	}
+	return false
+}
+
+// processKey returns a string that is equal for two processes only if they are exact duplicates
+func processKey(proc *Process) string {
+	var sb strings.Builder
+	for _, item := range slices.Sorted(maps.Keys(proc.Makes)) {
+		fmt.Fprintf(&sb, "%s=%d\x00", item, proc.Makes[item])
+	}
+	sb.WriteString("\x01")
+	for _, item := range slices.Sorted(maps.Keys(proc.Consumes)) {
+		fmt.Fprintf(&sb, "%s=%d\x00", item, proc.Consumes[item])
+	}
+	fmt.Fprintf(&sb, "\x01%g\x01%s\x01%t", proc.Time, strings.Join(proc.Facility, "\x00"), proc.Special)
+	return sb.String()
+}
+
+// recordPositions remembers where processes, facilities, power, items and technologies were defined in a data file
+func (df *DataFile) recordPositions(name string, root *yaml.Node) {
+	df.facilityPos = make(map[string]Position)
+	if _, facs := mappingEntry(root, "facilities"); facs != nil && facs.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(facs.Content); i += 2 {
+			facType, buildings := facs.Content[i], facs.Content[i+1]
+			if buildings.Kind != yaml.MappingNode {
+				continue
+			}
+			for j := 0; j+1 < len(buildings.Content); j += 2 {
+				fac := buildings.Content[j]
+				df.facilityPos[facType.Value+"\x00"+fac.Value] = nodePosition(name, fac)
+			}
+		}
+	}
+	df.powerPos = make(map[string]Position)
+	if _, power := mappingEntry(root, "power"); power != nil && power.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(power.Content); i += 2 {
+			df.powerPos[power.Content[i].Value] = nodePosition(name, power.Content[i])
+		}
+	}
+	if _, items := mappingEntry(root, "items"); items != nil && items.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(items.Content); i += 2 {
+			key := items.Content[i]
+			if item, ok := df.Items[key.Value]; ok {
+				item.pos = nodePosition(name, key)
+				df.Items[key.Value] = item
+			}
+		}
+	}
+	if _, techs := mappingEntry(root, "technologies"); techs != nil && techs.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(techs.Content); i += 2 {
+			key := techs.Content[i]
+			if tech, ok := df.Technologies[key.Value]; ok {
+				tech.pos = nodePosition(name, key)
+				df.Technologies[key.Value] = tech
+			}
+		}
+	}
+	for i, n := range sequenceEntries(root, "processes") {
+		if i < len(df.Processes) {
+			df.Processes[i].pos = nodePosition(name, n)
+		}
+	}
+}
+
+// mappingEntry finds a key in the top-level mapping of a YAML document, returning the key and value nodes
+func mappingEntry(root *yaml.Node, key string) (*yaml.Node, *yaml.Node) {
+	doc := root
+	if doc.Kind == yaml.DocumentNode && len(doc.Content) > 0 {
+		doc = doc.Content[0]
+	}
+	if doc.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	for i := 0; i+1 < len(doc.Content); i += 2 {
+		if doc.Content[i].Value == key {
+			return doc.Content[i], doc.Content[i+1]
+		}
+	}
+	return nil, nil
+}
+
+// sequenceEntries returns the entries of a top-level sequence in a YAML document
+func sequenceEntries(root *yaml.Node, key string) []*yaml.Node {
+	_, seq := mappingEntry(root, key)
+	if seq == nil || seq.Kind != yaml.SequenceNode {
+		return nil
+	}
+	return seq.Content
+}
+
+func nodePosition(name string, n *yaml.Node) Position {
+	return Position{File: name, Line: n.Line, Column: n.Column}
+}
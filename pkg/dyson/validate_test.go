@@ -0,0 +1,280 @@
+package dyson
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDataFile_Lint(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		problems []string
+	}{
+		{
+			name:     "valid data",
+			data:     testYAMLData,
+			problems: nil,
+		},
+		{
+			name: "facility problems",
+			data: `
+facilities:
+  smelter:
+    Arc Smelter: 0
+    Plane Smelter: -1
+  replicator:
+    Arc Smelter: 1
+processes: []
+`,
+			problems: []string{
+				"4:5: duplicate facility: Arc Smelter is in both replicator and smelter",
+				"4:5: facility rate is zero: Arc Smelter",
+				"5:5: facility rate is negative: Plane Smelter",
+			},
+		},
+		{
+			name: "power problems",
+			data: `
+facilities:
+  mine:
+    Mining Machine: 1
+power:
+  Mining Machine: -420
+  Mining Machnie: 420
+processes:
+  - makes:
+      Iron Ore: 1
+    time: 2
+    facility: [ mine ]
+`,
+			problems: []string{
+				"6:3: power is negative: Mining Machine",
+				"7:3: power given for unknown building: Mining Machnie",
+			},
+		},
+		{
+			name: "process problems",
+			data: `
+facilities:
+  mine:
+    Mining Machine: 1
+processes:
+  - makes:
+      Iron Ore: 1
+    time: 2
+    facility: [ mine ]
+  - makes: {}
+    time: 1
+    facility: [ mine ]
+  - makes:
+      Stone: 0
+    consumes:
+      Iron Ore: -1
+    time: 0
+    facility: [ mind ]
+  - makes:
+      Iron Ore: 1
+    time: 2
+    facility: [ mine ]
+  - makes:
+      Coal: 1
+    time: 1
+    facility: []
+`,
+			problems: []string{
+				"10:5: process makes nothing",
+				"13:5: process making Stone from Iron Ore: count of Stone is not positive",
+				"13:5: process making Stone from Iron Ore: count of Iron Ore is not positive",
+				"13:5: process making Stone from Iron Ore: time is not positive",
+				"13:5: process making Stone from Iron Ore: unknown facility type: mind",
+				"19:5: process making Iron Ore: duplicate of process at 6:5",
+				"23:5: process making Coal: no facility given",
+			},
+		},
+		{
+			name: "special process without alternative",
+			data: `
+facilities:
+  mine:
+    Mining Machine: 1
+processes:
+  - makes:
+      Iron Ore: 1
+    time: 2
+    facility: [ mine ]
+  - makes:
+      Iron Ore: 1
+      Unipolar Magnet: 1
+    time: 2
+    facility: [ mine ]
+    special: true
+`,
+			problems: []string{
+				"10:5: process making Iron Ore, Unipolar Magnet: special process is the only way to make Unipolar Magnet",
+			},
+		},
+		{
+			name: "item problems",
+			data: `
+facilities:
+  mine:
+    Mining Machine: 1
+items:
+  Iron Ore: { category: resource, stack: 100 }
+  Coal: { category: fuel, stack: -1 }
+  Iron Ingot: { category: metal, fuel: -2 }
+processes:
+  - makes:
+      Iron Ore: 1
+    time: 2
+    facility: [ mine ]
+  - makes:
+      Coal: 1
+    time: 2
+    facility: [ mine ]
+`,
+			problems: []string{
+				"7:3: item Coal: stack size is negative",
+				"7:3: item Coal: fuel item has no fuel value",
+				"8:3: item Iron Ingot: unknown category: metal",
+				"8:3: item Iron Ingot: fuel value is negative",
+				"8:3: item Iron Ingot is not made or used by any process",
+			},
+		},
+		{
+			name: "technology problems",
+			data: `
+facilities:
+  mine:
+    Mining Machine: 1
+items:
+  Iron Ore: { category: resource, tech: Mining }
+technologies:
+  Chicken: { requires: [ Egg ], cost: { Iron Ore: 1 } }
+  Egg: { requires: [ Chicken ], cost: { Iron Ore: 0 } }
+  Alchemy: { requires: [ Magic ], cost: { Gold: 5 } }
+processes:
+  - makes:
+      Iron Ore: 1
+    time: 2
+    facility: [ mine ]
+    tech: Smelting
+`,
+			problems: []string{
+				"6:3: item Iron Ore: unknown technology: Mining",
+				"8:3: technology Chicken requires itself",
+				"9:3: technology Egg: cost of Iron Ore is not positive",
+				"9:3: technology Egg requires itself",
+				"10:3: technology Alchemy: unknown prerequisite: Magic",
+				"10:3: technology Alchemy: cost uses unknown item: Gold",
+				"12:5: process making Iron Ore: unknown technology: Smelting",
+			},
+		},
+		{
+			name: "unmakeable item",
+			data: `
+facilities:
+  smelter:
+    Arc Smelter: 1
+processes:
+  - makes:
+      Iron Ingot: 1
+    consumes:
+      Iron Ore: 1
+    time: 1
+    facility: [ smelter ]
+`,
+			problems: []string{
+				"6:5: item cannot be made: Iron Ingot (used by process making Iron Ingot from Iron Ore)",
+				"6:5: item cannot be made: Iron Ore (used by process making Iron Ingot from Iron Ore)",
+			},
+		},
+		{
+			name: "recipe IDs",
+			data: `
+facilities:
+  mine:
+    Mining Machine: 1
+processes:
+  - makes: { Iron Ore: 1 }
+    time: 1
+    facility: [ mine ]
+    id: 1
+  - makes: { Copper Ore: 1 }
+    time: 1
+    facility: [ mine ]
+    id: 1
+  - makes: { Stone: 1 }
+    time: 1
+    facility: [ mine ]
+    id: -2
+`,
+			problems: []string{
+				"10:5: process making Copper Ore: recipe ID 1 is also used by the process at 6:5",
+				"14:5: process making Stone: recipe ID is negative",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			df, err := LoadData([]byte(tt.data))
+			if err != nil {
+				t.Fatalf("Failed to load test data: %v", err)
+			}
+			problems := df.Lint()
+			var got []string
+			for _, p := range problems {
+				got = append(got, p.String())
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.problems, "\n") {
+				t.Errorf("Lint() problems:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tt.problems, "\n"))
+			}
+
+			err = df.Validate()
+			if (err != nil) != (len(tt.problems) > 0) {
+				t.Errorf("Validate() error = %v, want %d problems", err, len(tt.problems))
+			}
+			var verr *ValidationError
+			if err != nil && (!errors.As(err, &verr) || len(verr.Problems) != len(tt.problems)) {
+				t.Errorf("Validate() should return a *ValidationError with all problems, got %v", err)
+			}
+		})
+	}
+}
+
+func TestDataFile_LintFileNames(t *testing.T) {
+	_, err := LoadDataLayers(DataLayer{Name: "bad.yml", Data: []byte(`
+facilities:
+  smelter:
+    Arc Smelter: 0
+processes: []
+`)})
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("LoadDataLayers() error = %v, want a *ValidationError", err)
+	}
+	problems := verr.Problems
+	if len(problems) != 1 || problems[0].String() != "bad.yml:4:5: facility rate is zero: Arc Smelter" {
+		t.Errorf("Lint() = %v, want a single problem at bad.yml:4:5", problems)
+	}
+}
+
+func TestPosition_String(t *testing.T) {
+	tests := []struct {
+		pos  Position
+		want string
+	}{
+		{pos: Position{}, want: ""},
+		{pos: Position{File: "data.yml"}, want: "data.yml"},
+		{pos: Position{Line: 3, Column: 5}, want: "3:5"},
+		{pos: Position{File: "data.yml", Line: 3, Column: 5}, want: "data.yml:3:5"},
+	}
+	for _, tt := range tests {
+		if got := tt.pos.String(); got != tt.want {
+			t.Errorf("Position%+v.String() = %q, want %q", tt.pos, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"fmt"
+	"github.com/ghjm/dyson/pkg/dyson"
+	"github.com/spf13/cobra"
+	"os"
+	"slices"
+	"strings"
+)
+
+// readPlan reads and parses a plan file
+func readPlan(fn string) (*dyson.Plan, error) {
+	data, err := os.ReadFile(fn)
+	if err != nil {
+		return nil, fmt.Errorf("error reading plan file: %w", err)
+	}
+	plan, err := dyson.LoadPlan(data)
+	if err != nil {
+		return nil, fmt.Errorf("error loading plan %s: %w", fn, err)
+	}
+	return plan, nil
+}
+
+func (a *app) planCmd() *cobra.Command {
+	planCmd := &cobra.Command{
+		Use:   "plan",
+		Short: "Work with plan files describing a whole factory",
+	}
+	runCmd := &cobra.Command{
+		Use:   "run plan.yml",
+		Short: "Calculate the full production chain for a plan file",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			df, err := a.loadResearchedData()
+			if err != nil {
+				return err
+			}
+			plan, err := readPlan(args[0])
+			if err != nil {
+				return err
+			}
+			ch, err := df.RunPlan(plan)
+			if err != nil {
+				return fmt.Errorf("error running plan: %w", err)
+			}
+			fmt.Printf("%s", ch.StringWithOpts(append(a.displayOpts(), dyson.WithBuildings())...))
+			err = printRawResources(ch, false, a.displayOpts()...)
+			if err != nil {
+				return err
+			}
+			item, rate := ch.ProliferatorDemand()
+			if item != "" {
+				fmt.Printf("\nSpraying uses %s of %s\n", dyson.FormatRate(rate, a.displayOpts()...), item)
+			}
+			return nil
+		},
+	}
+	bottleneckCmd := &cobra.Command{
+		Use:   "bottleneck plan.yml",
+		Short: "Find what limits the output of an existing factory",
+		Long: "Find what limits the output of an existing factory.  The plan's built section gives the number of " +
+			"buildings placed for each item, and the targets keep the proportions of their planned rates.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			df, err := a.loadResearchedData()
+			if err != nil {
+				return err
+			}
+			plan, err := readPlan(args[0])
+			if err != nil {
+				return err
+			}
+			ba, err := df.Bottlenecks(plan)
+			if err != nil {
+				return fmt.Errorf("error analysing plan: %w", err)
+			}
+			fmt.Print(ba.StringWithOpts(a.displayOpts()...))
+			return nil
+		},
+	}
+	a.addResearchFlags(runCmd, bottleneckCmd)
+	planCmd.AddCommand(runCmd, bottleneckCmd)
+	return planCmd
+}
+
+func (a *app) compareCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "compare a.yml b.yml",
+		Short: "Compare the production chains of two plans for the same targets",
+		Long: "Compare the production chains of two plans, such as before and after an upgrade, showing the change " +
+			"in rates, factories, raw resources and power.  If the second plan has no targets, it uses the first " +
+			"plan's targets.",
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			df, err := a.loadResearchedData()
+			if err != nil {
+				return err
+			}
+			var plans []*dyson.Plan
+			for _, fn := range args {
+				plan, err := readPlan(fn)
+				if err != nil {
+					return err
+				}
+				plans = append(plans, plan)
+			}
+			if len(plans[1].Targets) == 0 {
+				plans[1].Targets = plans[0].Targets
+			} else if !slices.Equal(plans[0].TargetItems(), plans[1].TargetItems()) {
+				return fmt.Errorf("plans have different targets: %s and %s",
+					strings.Join(plans[0].TargetItems(), ", "), strings.Join(plans[1].TargetItems(), ", "))
+			}
+			var chains []*dyson.ProductionChain
+			for i, plan := range plans {
+				ch, err := df.RunPlan(plan)
+				if err != nil {
+					return fmt.Errorf("error running plan %s: %w", args[i], err)
+				}
+				chains = append(chains, ch)
+			}
+			fmt.Printf("A: %s\nB: %s\n\n", args[0], args[1])
+			fmt.Print(dyson.CompareChains(chains[0], chains[1]).StringWithOpts(a.displayOpts()...))
+			return nil
+		},
+	}
+	a.addResearchFlags(cmd)
+	return cmd
+}
+
+func (a *app) reportCmd() *cobra.Command {
+	var planFile string
+	var haveItems []string
+	var output string
+	var title string
+	cmd := &cobra.Command{
+		Use:   "report [item:rate...]",
+		Short: "Write a self-contained HTML report of a production chain",
+		Long: "Write a self-contained HTML report of a production chain, with a graph, a table of the steps, the " +
+			"raw resources and the power drawn.  The chain is given either as targets like the chain command, or " +
+			"as a plan file with --plan.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			df, err := a.loadResearchedData()
+			if err != nil {
+				return err
+			}
+			var ch *dyson.ProductionChain
+			if planFile != "" {
+				if len(args) > 0 {
+					return fmt.Errorf("give either targets or a plan file, not both")
+				}
+				plan, err := readPlan(planFile)
+				if err != nil {
+					return err
+				}
+				ch, err = df.RunPlan(plan)
+				if err != nil {
+					return fmt.Errorf("error running plan: %w", err)
+				}
+			} else {
+				if len(args) == 0 {
+					return fmt.Errorf("no targets given: give targets or a plan file with --plan")
+				}
+				res, err := df.NewPlanner(dyson.WithTargets(args...), dyson.WithHave(haveItems...),
+					dyson.WithRateUnit(a.unit)).Chain()
+				if err != nil {
+					return err
+				}
+				ch = res.Chain
+			}
+			if title == "" {
+				title = "Production plan"
+			}
+			report, err := ch.HTMLReport(title, a.displayOpts()...)
+			if err != nil {
+				return fmt.Errorf("error writing report: %w", err)
+			}
+			if output == "-" {
+				fmt.Print(report)
+				return nil
+			}
+			err = os.WriteFile(output, []byte(report), 0o644)
+			if err != nil {
+				return fmt.Errorf("error writing report: %w", err)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&planFile, "plan", "", "Plan file to report on instead of targets")
+	cmd.Flags().StringArrayVar(&haveItems, "have", []string{}, "Items you already have (excludes them from the chain)")
+	cmd.Flags().StringVarP(&output, "output", "o", "report.html", "File to write the report to, or - for standard output")
+	cmd.Flags().StringVar(&title, "title", "", "Title of the report")
+	a.addResearchFlags(cmd)
+	return cmd
+}
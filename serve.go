@@ -0,0 +1,139 @@
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"github.com/ghjm/dyson/pkg/dyson"
+	"github.com/spf13/cobra"
+	"io/fs"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+)
+
+//go:embed ui
+var uiFiles embed.FS
+
+type server struct {
+	df *dyson.DataFile
+}
+
+type chainResponse struct {
+	Steps   []chainStepResponse `json:"steps"`
+	Raw     map[string]float64  `json:"raw"`
+	Mermaid string              `json:"mermaid"`
+	SVG     string              `json:"svg"`
+}
+
+type chainStepResponse struct {
+	Target     string   `json:"target"`
+	Rate       float64  `json:"rate"`
+	Inputs     []string `json:"inputs"`
+	Facilities []string `json:"facilities"`
+}
+
+func (a *app) serveCmd() *cobra.Command {
+	var listenAddr string
+	var serveUI bool
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Serve production chain calculations over HTTP, optionally with a web UI",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			df, err := a.loadData()
+			if err != nil {
+				return err
+			}
+			handler, err := newServer(df, serveUI)
+			if err != nil {
+				return fmt.Errorf("error creating server: %w", err)
+			}
+			srv := &http.Server{
+				Addr:              listenAddr,
+				Handler:           handler,
+				ReadHeaderTimeout: 10 * time.Second,
+			}
+			fmt.Printf("Listening on http://%s/\n", listenAddr)
+			return srv.ListenAndServe()
+		},
+	}
+	cmd.Flags().StringVar(&listenAddr, "listen", "localhost:8080", "Address to listen on")
+	cmd.Flags().BoolVar(&serveUI, "ui", false, "Serve the web UI in addition to the JSON API")
+	return cmd
+}
+
+// newServer returns an HTTP handler serving the JSON API, and optionally the embedded web UI
+func newServer(df *dyson.DataFile, ui bool) (http.Handler, error) {
+	s := &server{df: df}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/items", s.handleItems)
+	mux.HandleFunc("GET /api/chain", s.handleChain)
+	if ui {
+		uiRoot, err := fs.Sub(uiFiles, "ui")
+		if err != nil {
+			return nil, err
+		}
+		mux.Handle("GET /", http.FileServerFS(uiRoot))
+	}
+	return mux, nil
+}
+
+func (s *server) handleItems(w http.ResponseWriter, r *http.Request) {
+	items := make(map[string]struct{})
+	for _, proc := range s.df.Processes {
+		for m := range proc.Makes {
+			items[m] = struct{}{}
+		}
+	}
+	writeJSON(w, slices.Sorted(maps.Keys(items)))
+}
+
+func (s *server) handleChain(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	var factoriesMode bool
+	if f := query.Get("factories"); f != "" {
+		var err error
+		factoriesMode, err = strconv.ParseBool(f)
+		if err != nil {
+			http.Error(w, "invalid factories value: "+f, http.StatusBadRequest)
+			return
+		}
+	}
+	res, err := s.df.NewPlanner(dyson.WithTargets(query["target"]...), dyson.WithHave(query["have"]...),
+		dyson.WithExclude(query["exclude"]...), dyson.WithFactoryRates(factoriesMode)).Chain()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ch := res.Chain
+	resp := chainResponse{
+		Steps:   []chainStepResponse{},
+		Raw:     ch.RawResources(),
+		Mermaid: ch.MermaidGraph(),
+		SVG:     ch.SVG(),
+	}
+	for _, step := range ch.Steps {
+		sr := chainStepResponse{
+			Target:     step.Target,
+			Rate:       step.Rate,
+			Inputs:     []string{},
+			Facilities: []string{},
+		}
+		if step.Process != nil {
+			sr.Inputs = slices.Sorted(maps.Keys(step.Process.Consumes))
+			sr.Facilities = step.Process.Facility
+		}
+		resp.Steps = append(resp.Steps, sr)
+	}
+	writeJSON(w, resp)
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
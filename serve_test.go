@@ -0,0 +1,177 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/ghjm/dyson/pkg/dyson"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// newTestServer starts a server for the embedded data, with the UI
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	df, err := dyson.LoadData(dataFileContent)
+	if err != nil {
+		t.Fatalf("Failed to load data: %v", err)
+	}
+	handler, err := newServer(df, true)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	ts := httptest.NewServer(handler)
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+// get fetches a path from the server, returning the status code and body
+func get(t *testing.T, ts *httptest.Server, path string) (int, []byte) {
+	t.Helper()
+	resp, err := http.Get(ts.URL + path)
+	if err != nil {
+		t.Fatalf("Failed to get %s: %v", path, err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", path, err)
+	}
+	return resp.StatusCode, body
+}
+
+func TestServer_Items(t *testing.T) {
+	ts := newTestServer(t)
+	status, body := get(t, ts, "/api/items")
+	if status != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", status, http.StatusOK, body)
+	}
+	var items []string
+	err := json.Unmarshal(body, &items)
+	if err != nil {
+		t.Fatalf("Failed to parse items: %v", err)
+	}
+	if !slices.IsSorted(items) {
+		t.Errorf("items are not sorted: %v", items)
+	}
+	if !slices.Contains(items, "Gear") {
+		t.Errorf("items do not include Gear: %v", items)
+	}
+}
+
+func TestServer_Chain(t *testing.T) {
+	ts := newTestServer(t)
+
+	tests := []struct {
+		name      string
+		query     string
+		status    int
+		errorText string             // for failed requests, text the error must contain
+		rates     map[string]float64 // for successful requests, rates of steps that must be in the chain
+		absent    []string           // steps that must not be in the chain
+	}{
+		{
+			name:   "good request",
+			query:  "target=Gear:2",
+			status: http.StatusOK,
+			rates:  map[string]float64{"Gear": 2, "Iron Ingot": 2, "Iron Ore": 2},
+		},
+		{
+			name:      "bad target",
+			query:     "target=Widget:1",
+			status:    http.StatusBadRequest,
+			errorText: "Widget",
+		},
+		{
+			name:      "bad rate",
+			query:     "target=Gear:fast",
+			status:    http.StatusBadRequest,
+			errorText: "invalid rate",
+		},
+		{
+			name:      "bad factories value",
+			query:     "target=Gear:2&factories=maybe",
+			status:    http.StatusBadRequest,
+			errorText: "invalid factories value: maybe",
+		},
+		{
+			name:   "factories",
+			query:  "target=Gear:2&factories=true",
+			status: http.StatusOK,
+			rates:  map[string]float64{"Gear": 2},
+		},
+		{
+			name:   "have items",
+			query:  "target=Gear:2&have=Iron+Ingot",
+			status: http.StatusOK,
+			rates:  map[string]float64{"Gear": 2},
+			absent: []string{"Iron Ore"},
+		},
+		{
+			name:      "excluded input",
+			query:     "target=Gear:2&exclude=Iron+Ingot",
+			status:    http.StatusBadRequest,
+			errorText: "every process for Gear uses an excluded item",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := get(t, ts, "/api/chain?"+tt.query)
+			if status != tt.status {
+				t.Fatalf("status = %d, want %d: %s", status, tt.status, body)
+			}
+			if status != http.StatusOK {
+				if !strings.Contains(string(body), tt.errorText) {
+					t.Errorf("error = %q, want it to contain %q", body, tt.errorText)
+				}
+				return
+			}
+			var resp chainResponse
+			err := json.Unmarshal(body, &resp)
+			if err != nil {
+				t.Fatalf("Failed to parse chain: %v", err)
+			}
+			steps := make(map[string]chainStepResponse)
+			for _, s := range resp.Steps {
+				steps[s.Target] = s
+			}
+			for item, want := range tt.rates {
+				s, ok := steps[item]
+				if !ok {
+					t.Errorf("chain has no step for %s", item)
+					continue
+				}
+				if s.Rate != want {
+					t.Errorf("rate of %s = %v, want %v", item, s.Rate, want)
+				}
+			}
+			for _, item := range tt.absent {
+				if _, ok := steps[item]; ok {
+					t.Errorf("chain has a step for %s", item)
+				}
+			}
+			if !strings.HasPrefix(resp.SVG, "<svg") {
+				t.Errorf("svg does not start with <svg: %.40q", resp.SVG)
+			}
+		})
+	}
+}
+
+func TestServer_UI(t *testing.T) {
+	ts := newTestServer(t)
+	status, body := get(t, ts, "/")
+	if status != http.StatusOK {
+		t.Fatalf("status = %d, want %d", status, http.StatusOK)
+	}
+	// The page must work offline, so it may not load anything from elsewhere
+	for _, s := range []string{"http://", "https://", "//cdn"} {
+		if strings.Contains(string(body), s) {
+			t.Errorf("UI refers to %q", s)
+		}
+	}
+}
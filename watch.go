@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/fsnotify/fsnotify"
+	"io"
+	"path/filepath"
+	"time"
+)
+
+// watchDelay is how long to wait after a change before reloading, so that a burst of writes from an editor saving a
+// file only causes one reload
+const watchDelay = 200 * time.Millisecond
+
+// watchFiles runs fn, and then runs it again whenever one of the files changes, until the context is done.  Errors,
+// and a banner before each reload, are printed to status rather than mixed into fn's output.  Errors don't end the
+// watch, so that a broken edit can be fixed and saved again.
+func watchFiles(ctx context.Context, files []string, status io.Writer, fn func() error) error {
+	if len(files) == 0 {
+		return fmt.Errorf("--watch needs a --data file to watch")
+	}
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("error watching files: %w", err)
+	}
+	defer func() {
+		_ = w.Close()
+	}()
+
+	// Watch the directories rather than the files, because many editors save by replacing the file
+	watched := make(map[string]struct{})
+	dirs := make(map[string]struct{})
+	for _, file := range files {
+		path, err := filepath.Abs(file)
+		if err != nil {
+			return fmt.Errorf("error watching %s: %w", file, err)
+		}
+		watched[path] = struct{}{}
+		dir := filepath.Dir(path)
+		if _, ok := dirs[dir]; ok {
+			continue
+		}
+		dirs[dir] = struct{}{}
+		err = w.Add(dir)
+		if err != nil {
+			return fmt.Errorf("error watching %s: %w", file, err)
+		}
+	}
+
+	run := func() {
+		err := fn()
+		if err != nil {
+			fmt.Fprintf(status, "Error: %v\n", err)
+		}
+	}
+	run()
+
+	var reload <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case ev, ok := <-w.Events:
+			if !ok {
+				return nil
+			}
+			if _, ok := watched[filepath.Clean(ev.Name)]; ok && !ev.Has(fsnotify.Chmod) {
+				reload = time.After(watchDelay)
+			}
+		case err, ok := <-w.Errors:
+			if !ok {
+				return nil
+			}
+			fmt.Fprintf(status, "Error watching files: %v\n", err)
+		case <-reload:
+			reload = nil
+			fmt.Fprintf(status, "\n=== Reloaded at %s ===\n\n", time.Now().Format(time.TimeOnly))
+			run()
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWatchFiles_NoFiles(t *testing.T) {
+	err := watchFiles(context.Background(), nil, &bytes.Buffer{}, func() error { return nil })
+	if err == nil || !strings.Contains(err.Error(), "needs a --data file") {
+		t.Errorf("watchFiles() error = %v, want one asking for a --data file", err)
+	}
+}
+
+func TestWatchFiles(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "data.yml")
+	other := filepath.Join(dir, "other.yml")
+	write := func(fn string) {
+		t.Helper()
+		err := os.WriteFile(fn, []byte("overlay: true\n"), 0o644)
+		if err != nil {
+			t.Fatalf("Failed to write %s: %v", fn, err)
+		}
+	}
+	write(file)
+
+	// The second run fails, as if a broken edit was saved
+	runs := make(chan int, 10)
+	n := 0
+	fn := func() error {
+		n++
+		runs <- n
+		if n == 2 {
+			return fmt.Errorf("broken edit")
+		}
+		return nil
+	}
+	waitRun := func(want int) {
+		t.Helper()
+		select {
+		case got := <-runs:
+			if got != want {
+				t.Fatalf("run %d, want run %d", got, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for run %d", want)
+		}
+	}
+	noRun := func(why string) {
+		t.Helper()
+		select {
+		case got := <-runs:
+			t.Fatalf("run %d after %s", got, why)
+		case <-time.After(3 * watchDelay):
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var status bytes.Buffer
+	done := make(chan error)
+	go func() {
+		done <- watchFiles(ctx, []string{file}, &status, fn)
+	}()
+	waitRun(1)
+
+	// A burst of writes, as from an editor saving, is only one reload
+	for i := 0; i < 3; i++ {
+		write(file)
+	}
+	waitRun(2)
+	noRun("a burst of writes")
+
+	// Other files in the same directory are ignored
+	write(other)
+	noRun("writing another file")
+
+	// The error from the second run doesn't end the watch
+	write(file)
+	waitRun(3)
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("watchFiles() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("watchFiles() did not stop when the context was cancelled")
+	}
+	out := status.String()
+	if got := strings.Count(out, "=== Reloaded at"); got != 2 {
+		t.Errorf("status has %d reload banners, want 2:\n%s", got, out)
+	}
+	if !strings.Contains(out, "Error: broken edit\n") {
+		t.Errorf("status does not show the error:\n%s", out)
+	}
+}